@@ -0,0 +1,219 @@
+package jni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+func (v *vm) OnLoad(ctx context.Context, module debugger.Module) (int32, error) {
+	addr, err := module.FindSymbol("JNI_OnLoad")
+	if err != nil {
+		return 0, fmt.Errorf("%w: JNI_OnLoad", ErrNativeNotFound)
+	}
+	var version int32
+	err = v.call(ctx, addr, &version, uintptr(v.vmAddr), uintptr(0))
+	if err != nil {
+		return 0, err
+	}
+	if obj := v.ExceptionClear(); obj != nil {
+		return version, &Exception{Object: obj}
+	}
+	return version, nil
+}
+
+func (v *vm) Call(ctx context.Context, module debugger.Module, cls Class, name, sig string, this Object, args ...any) (any, error) {
+	c, ok := cls.(*class)
+	if !ok {
+		return nil, ErrReferenceInvalid
+	}
+	parsed, err := parseSignature(sig)
+	if err != nil {
+		return nil, err
+	} else if len(args) != len(parsed.params) {
+		return nil, debugger.ErrArgumentInvalid
+	}
+	addr, err := v.findNative(module, c, name, sig)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]any, 0, len(args)+2)
+	raw = append(raw, uintptr(v.envAddr))
+	if this != nil {
+		raw = append(raw, uintptr(v.ref(this)))
+	} else {
+		raw = append(raw, uintptr(v.ref(c)))
+	}
+	for i, param := range parsed.params {
+		if isReference(param[0]) {
+			raw = append(raw, uintptr(v.ref(v.toObject(param, args[i]))))
+		} else {
+			raw = append(raw, typed(param[0], toRaw(param[0], args[i])))
+		}
+	}
+	var result any
+	typ := parsed.ret[0]
+	switch typ {
+	case 'V':
+		err = v.call(ctx, addr, nil, raw...)
+	case 'F':
+		var f float32
+		err = v.call(ctx, addr, &f, raw...)
+		result = f
+	case 'D':
+		var d float64
+		err = v.call(ctx, addr, &d, raw...)
+		result = d
+	case 'J':
+		var j int64
+		err = v.call(ctx, addr, &j, raw...)
+		result = j
+	case 'L', '[':
+		var h uintptr
+		err = v.call(ctx, addr, &h, raw...)
+		result = v.object(uint64(h))
+	default:
+		var i int32
+		err = v.call(ctx, addr, &i, raw...)
+		result = fromRaw(typ, uint64(uint32(i)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if obj := v.ExceptionClear(); obj != nil {
+		return nil, &Exception{Object: obj}
+	}
+	return result, nil
+}
+
+func (v *vm) call(ctx context.Context, addr uint64, ret any, args ...any) error {
+	task, err := v.dbg.CreateTask(ctx)
+	if err != nil {
+		return err
+	}
+	defer task.Close()
+	err = task.Context().ArgWrite(debugger.Calling_Default, args...)
+	if err != nil {
+		return err
+	}
+	err = v.dbg.CallTaskOf(task, addr)
+	if err != nil {
+		return err
+	}
+	err = task.SyncRun()
+	if err != nil {
+		return err
+	} else if ret == nil {
+		return nil
+	}
+	return task.Context().RetExtract(ret)
+}
+
+func (v *vm) findNative(module debugger.Module, c *class, name, sig string) (uint64, error) {
+	if addr, ok := c.native(name, sig); ok {
+		return addr, nil
+	}
+	short := "Java_" + mangle(c.name) + "_" + mangle(name)
+	if addr, err := module.FindSymbol(short); err == nil {
+		return addr, nil
+	}
+	long := short + "__" + mangle(sig[1:strings.IndexByte(sig, ')')])
+	if addr, err := module.FindSymbol(long); err == nil {
+		return addr, nil
+	}
+	return 0, fmt.Errorf("%w: %s.%s%s", ErrNativeNotFound, c.name, name, sig)
+}
+
+func (v *vm) invoke(target *class, id *methodID, this Object, args []any) (any, error) {
+	var method Method
+	if target != nil {
+		method = target.method(id.name, id.sig, id.static)
+	}
+	if method == nil && id.class != nil && id.class != target {
+		method = id.class.method(id.name, id.sig, id.static)
+	}
+	if method == nil {
+		v.throwNew("java/lang/NoSuchMethodError", id.class.name+"."+id.name+id.sig)
+		return nil, nil
+	}
+	return method(v, this, args)
+}
+
+func (v *vm) raise(err error) {
+	var ex *Exception
+	if errors.As(err, &ex) && ex.Object != nil {
+		v.Throw(ex.Object)
+		return
+	}
+	v.throwNew("java/lang/RuntimeException", err.Error())
+}
+
+func (v *vm) toObject(sig string, value any) Object {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case Object:
+		return value
+	case string:
+		return v.NewString(value)
+	case []bool:
+		return v.NewArray("[Z", value)
+	case []byte:
+		return v.NewArray("[B", value)
+	case []uint16:
+		return v.NewArray("[C", value)
+	case []int16:
+		return v.NewArray("[S", value)
+	case []int32:
+		return v.NewArray("[I", value)
+	case []int64:
+		return v.NewArray("[J", value)
+	case []float32:
+		return v.NewArray("[F", value)
+	case []float64:
+		return v.NewArray("[D", value)
+	case []string:
+		elems := make([]Object, len(value))
+		for i, s := range value {
+			elems[i] = v.NewString(s)
+		}
+		return v.NewArray("[Ljava/lang/String;", elems)
+	case []Object:
+		if !strings.HasPrefix(sig, "[") {
+			sig = "[Ljava/lang/Object;"
+		}
+		return v.NewArray(sig, value)
+	}
+	name := "java/lang/Object"
+	if strings.HasPrefix(sig, "L") {
+		name = sig[1 : len(sig)-1]
+	}
+	return v.NewObject(v.DefineClass(name, nil), value)
+}
+
+func mangle(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '/':
+			sb.WriteByte('_')
+		case r == '_':
+			sb.WriteString("_1")
+		case r == ';':
+			sb.WriteString("_2")
+		case r == '[':
+			sb.WriteString("_3")
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		default:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&sb, "_0%04x", u)
+			}
+		}
+	}
+	return sb.String()
+}
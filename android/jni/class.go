@@ -0,0 +1,135 @@
+package jni
+
+import (
+	"sync"
+)
+
+type Method = func(vm VM, this Object, args []any) (any, error)
+
+type Object interface {
+	Class() Class
+	Value() any
+	Field(name string) any
+	SetField(name string, value any)
+}
+
+type Class interface {
+	Object
+	Name() string
+	Super() Class
+	AddMethod(name, sig string, method Method)
+	AddStaticMethod(name, sig string, method Method)
+	IsAssignableFrom(other Class) bool
+}
+
+type object struct {
+	mu     sync.RWMutex
+	class  Class
+	value  any
+	fields map[string]any
+}
+
+type class struct {
+	object
+	name    string
+	super   Class
+	methods map[string]Method
+	statics map[string]Method
+	natives map[string]uint64
+}
+
+func (o *object) Class() Class {
+	return o.class
+}
+
+func (o *object) Value() any {
+	return o.value
+}
+
+func (o *object) Field(name string) any {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.fields[name]
+}
+
+func (o *object) SetField(name string, value any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.fields == nil {
+		o.fields = make(map[string]any)
+	}
+	o.fields[name] = value
+}
+
+func (c *class) Value() any {
+	return c
+}
+
+func (c *class) Name() string {
+	return c.name
+}
+
+func (c *class) Super() Class {
+	return c.super
+}
+
+func (c *class) AddMethod(name, sig string, method Method) {
+	c.mu.Lock()
+	c.methods[name+sig] = method
+	c.mu.Unlock()
+}
+
+func (c *class) AddStaticMethod(name, sig string, method Method) {
+	c.mu.Lock()
+	c.statics[name+sig] = method
+	c.mu.Unlock()
+}
+
+func (c *class) IsAssignableFrom(other Class) bool {
+	for ; other != nil; other = other.Super() {
+		if other == Class(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *class) method(name, sig string, static bool) Method {
+	for cur := Class(c); cur != nil; cur = cur.Super() {
+		impl, ok := cur.(*class)
+		if !ok {
+			break
+		}
+		impl.mu.RLock()
+		var method Method
+		if static {
+			method = impl.statics[name+sig]
+		} else {
+			method = impl.methods[name+sig]
+		}
+		impl.mu.RUnlock()
+		if method != nil {
+			return method
+		}
+	}
+	return nil
+}
+
+func (c *class) native(name, sig string) (uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	addr, ok := c.natives[name+sig]
+	return addr, ok
+}
+
+func (c *class) registerNative(name, sig string, addr uint64) {
+	c.mu.Lock()
+	c.natives[name+sig] = addr
+	c.mu.Unlock()
+}
+
+func (c *class) unregisterNatives() {
+	c.mu.Lock()
+	clear(c.natives)
+	c.mu.Unlock()
+}
@@ -0,0 +1,805 @@
+package jni
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"unicode/utf16"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+const (
+	callTypes  = "LZBCSIJFDV"
+	fieldTypes = "LZBCSIJFD"
+	arrayTypes = "ZBCSIJFD"
+
+	envCallMethod        = 34
+	envCallNonvirtual    = 64
+	envGetFieldID        = 94
+	envGetField          = 95
+	envSetField          = 104
+	envGetStaticMethodID = 113
+	envCallStaticMethod  = 114
+	envGetStaticFieldID  = 144
+	envGetStaticField    = 145
+	envSetStaticField    = 154
+	envNewString         = 163
+	envNewArray          = 175
+	envGetArrayElements  = 183
+	envReleaseArrayElems = 191
+	envGetArrayRegion    = 199
+	envSetArrayRegion    = 207
+	envRegisterNatives   = 215
+	envFunctionCount     = 233
+	vmFunctionCount      = 8
+)
+
+var typeNames = map[byte]string{
+	'L': "Object", 'Z': "Boolean", 'B': "Byte", 'C': "Char", 'S': "Short",
+	'I': "Int", 'J': "Long", 'F': "Float", 'D': "Double", 'V': "Void",
+}
+
+var envFunctions = func() []string {
+	names := make([]string, envFunctionCount)
+	copy(names[4:], []string{
+		"GetVersion", "DefineClass", "FindClass", "FromReflectedMethod", "FromReflectedField",
+		"ToReflectedMethod", "GetSuperclass", "IsAssignableFrom", "ToReflectedField", "Throw",
+		"ThrowNew", "ExceptionOccurred", "ExceptionDescribe", "ExceptionClear", "FatalError",
+		"PushLocalFrame", "PopLocalFrame", "NewGlobalRef", "DeleteGlobalRef", "DeleteLocalRef",
+		"IsSameObject", "NewLocalRef", "EnsureLocalCapacity", "AllocObject", "NewObject",
+		"NewObjectV", "NewObjectA", "GetObjectClass", "IsInstanceOf", "GetMethodID",
+	})
+	for i := range len(callTypes) {
+		t := typeNames[callTypes[i]]
+		for j, suffix := range []string{"", "V", "A"} {
+			names[envCallMethod+i*3+j] = "Call" + t + "Method" + suffix
+			names[envCallNonvirtual+i*3+j] = "CallNonvirtual" + t + "Method" + suffix
+			names[envCallStaticMethod+i*3+j] = "CallStatic" + t + "Method" + suffix
+		}
+	}
+	names[envGetFieldID] = "GetFieldID"
+	names[envGetStaticMethodID] = "GetStaticMethodID"
+	names[envGetStaticFieldID] = "GetStaticFieldID"
+	for i := range len(fieldTypes) {
+		t := typeNames[fieldTypes[i]]
+		names[envGetField+i] = "Get" + t + "Field"
+		names[envSetField+i] = "Set" + t + "Field"
+		names[envGetStaticField+i] = "GetStatic" + t + "Field"
+		names[envSetStaticField+i] = "SetStatic" + t + "Field"
+	}
+	copy(names[envNewString:], []string{
+		"NewString", "GetStringLength", "GetStringChars", "ReleaseStringChars", "NewStringUTF",
+		"GetStringUTFLength", "GetStringUTFChars", "ReleaseStringUTFChars", "GetArrayLength", "NewObjectArray",
+		"GetObjectArrayElement", "SetObjectArrayElement",
+	})
+	for i := range len(arrayTypes) {
+		t := typeNames[arrayTypes[i]]
+		names[envNewArray+i] = "New" + t + "Array"
+		names[envGetArrayElements+i] = "Get" + t + "ArrayElements"
+		names[envReleaseArrayElems+i] = "Release" + t + "ArrayElements"
+		names[envGetArrayRegion+i] = "Get" + t + "ArrayRegion"
+		names[envSetArrayRegion+i] = "Set" + t + "ArrayRegion"
+	}
+	copy(names[envRegisterNatives:], []string{
+		"RegisterNatives", "UnregisterNatives", "MonitorEnter", "MonitorExit", "GetJavaVM",
+		"GetStringRegion", "GetStringUTFRegion", "GetPrimitiveArrayCritical", "ReleasePrimitiveArrayCritical", "GetStringCritical",
+		"ReleaseStringCritical", "NewWeakGlobalRef", "DeleteWeakGlobalRef", "ExceptionCheck", "NewDirectByteBuffer",
+		"GetDirectBufferAddress", "GetDirectBufferCapacity", "GetObjectRefType",
+	})
+	return names
+}()
+
+var vmFunctions = []string{"", "", "", "DestroyJavaVM", "AttachCurrentThread", "DetachCurrentThread", "GetEnv", "AttachCurrentThreadAsDaemon"}
+
+type envCall struct {
+	vm   *vm
+	ctx  debugger.Context
+	args debugger.Args
+}
+
+func (v *vm) handleEnv(ctx debugger.Context, data any) {
+	index := data.(int)
+	args, err := ctx.GetArgs(debugger.Calling_Default)
+	if err == nil {
+		var env uintptr
+		err = args.Extract(&env)
+	}
+	if err == nil {
+		call := &envCall{vm: v, ctx: ctx, args: args}
+		err = call.dispatch(index)
+	}
+	if err != nil {
+		if task, ok := ctx.(debugger.Task); ok {
+			task.CancelCause(fmt.Errorf("jni %s: %w", envFunctions[index], err))
+		}
+		return
+	}
+	ctx.Return()
+}
+
+func (v *vm) handleVM(ctx debugger.Context, data any) {
+	var ret int32 = JNI_OK
+	switch index := data.(int); index {
+	case 4, 6, 7:
+		var vmAddr, penv uintptr
+		if ctx.ArgExtract(debugger.Calling_Default, &vmAddr, &penv) != nil || penv == 0 {
+			ret = JNI_ERR
+			break
+		}
+		v.writeWord(ctx, uint64(penv), v.envAddr)
+	}
+	ctx.RetWrite(ret)
+	ctx.Return()
+}
+
+func (c *envCall) dispatch(index int) error {
+	v := c.vm
+	switch {
+	case index >= envCallMethod && index < envCallNonvirtual:
+		return c.callMethod(index-envCallMethod, false, false)
+	case index >= envCallNonvirtual && index < envGetFieldID:
+		return c.callMethod(index-envCallNonvirtual, true, false)
+	case index >= envCallStaticMethod && index < envGetStaticFieldID:
+		return c.callMethod(index-envCallStaticMethod, false, true)
+	case index >= envGetField && index < envSetField:
+		return c.getField()
+	case index >= envSetField && index < envGetStaticMethodID:
+		return c.setField(fieldTypes[index-envSetField])
+	case index >= envGetStaticField && index < envSetStaticField:
+		return c.getField()
+	case index >= envSetStaticField && index < envNewString:
+		return c.setField(fieldTypes[index-envSetStaticField])
+	case index >= envNewArray && index < envGetArrayElements:
+		return c.newArray(arrayTypes[index-envNewArray])
+	case index >= envGetArrayElements && index < envReleaseArrayElems:
+		return c.getArrayElements()
+	case index >= envReleaseArrayElems && index < envGetArrayRegion:
+		return c.releaseArrayElements()
+	case index >= envGetArrayRegion && index < envSetArrayRegion:
+		return c.arrayRegion(false)
+	case index >= envSetArrayRegion && index < envRegisterNatives:
+		return c.arrayRegion(true)
+	}
+	switch envFunctions[index] {
+	case "GetVersion":
+		return c.ctx.RetWrite(int32(JNI_VERSION_1_6))
+	case "FindClass":
+		var name string
+		if err := c.args.Extract(&name); err != nil {
+			return err
+		}
+		class, ok := v.FindClass(name)
+		if !ok {
+			v.throwNew("java/lang/NoClassDefFoundError", name)
+		}
+		return c.retObject(class)
+	case "GetSuperclass":
+		class, err := c.classArg()
+		if err != nil || class.super == nil {
+			return c.retObject(nil)
+		}
+		return c.retObject(class.super)
+	case "IsAssignableFrom":
+		a, b := c.ref(), c.ref()
+		ca, _ := v.object(a).(Class)
+		cb, _ := v.object(b).(Class)
+		return c.retBool(ca != nil && cb != nil && cb.IsAssignableFrom(ca))
+	case "Throw":
+		v.Throw(v.object(c.ref()))
+		return c.ctx.RetWrite(int32(0))
+	case "ThrowNew":
+		class, err := c.classArg()
+		if err != nil {
+			return err
+		}
+		var msg string
+		c.args.Extract(&msg)
+		v.Throw(v.NewObject(class, msg))
+		return c.ctx.RetWrite(int32(0))
+	case "ExceptionOccurred":
+		v.mu.RLock()
+		obj := v.exception
+		v.mu.RUnlock()
+		return c.retObject(obj)
+	case "ExceptionDescribe":
+		return nil
+	case "ExceptionClear":
+		v.ExceptionClear()
+		return nil
+	case "ExceptionCheck":
+		v.mu.RLock()
+		pending := v.exception != nil
+		v.mu.RUnlock()
+		return c.retBool(pending)
+	case "FatalError":
+		var msg string
+		c.args.Extract(&msg)
+		return fmt.Errorf("fatal error: %s", msg)
+	case "PushLocalFrame", "EnsureLocalCapacity", "MonitorEnter", "MonitorExit":
+		return c.ctx.RetWrite(int32(0))
+	case "PopLocalFrame", "NewGlobalRef", "NewLocalRef", "NewWeakGlobalRef":
+		return c.ctx.RetWrite(uintptr(c.ref()))
+	case "DeleteGlobalRef", "DeleteLocalRef", "DeleteWeakGlobalRef":
+		return nil
+	case "IsSameObject":
+		a, b := c.ref(), c.ref()
+		return c.retBool(v.object(a) == v.object(b))
+	case "GetObjectRefType":
+		if v.object(c.ref()) == nil {
+			return c.ctx.RetWrite(int32(0))
+		}
+		return c.ctx.RetWrite(int32(1))
+	case "AllocObject":
+		class, err := c.classArg()
+		if err != nil {
+			return err
+		}
+		return c.retObject(v.NewObject(class, nil))
+	case "NewObject", "NewObjectV", "NewObjectA":
+		return c.newObject(envFunctions[index])
+	case "GetObjectClass":
+		obj := v.object(c.ref())
+		if obj == nil {
+			return ErrReferenceInvalid
+		}
+		return c.retObject(obj.Class())
+	case "IsInstanceOf":
+		obj := v.object(c.ref())
+		class, err := c.classArg()
+		if err != nil {
+			return err
+		}
+		return c.retBool(obj == nil || class.IsAssignableFrom(obj.Class()))
+	case "GetMethodID", "GetStaticMethodID":
+		return c.getMethodID(index == envGetStaticMethodID)
+	case "GetFieldID", "GetStaticFieldID":
+		return c.getFieldID(index == envGetStaticFieldID)
+	case "NewString":
+		var ptr uintptr
+		var n int32
+		if err := c.args.Extract(&ptr, &n); err != nil {
+			return err
+		}
+		buf, err := c.ctx.ToPointer(uint64(ptr)).MemRead(uint64(n) * 2)
+		if err != nil {
+			return err
+		}
+		chars := make([]uint16, n)
+		binary.Decode(buf, binary.LittleEndian, chars)
+		return c.retObject(v.NewString(string(utf16.Decode(chars))))
+	case "NewStringUTF":
+		var s uintptr
+		if err := c.args.Extract(&s); err != nil {
+			return err
+		} else if s == 0 {
+			return c.retObject(nil)
+		}
+		str, err := c.ctx.ToPointer(uint64(s)).MemReadString()
+		if err != nil {
+			return err
+		}
+		return c.retObject(v.NewString(str))
+	case "GetStringLength":
+		return c.ctx.RetWrite(int32(len(utf16.Encode([]rune(c.stringArg())))))
+	case "GetStringUTFLength":
+		return c.ctx.RetWrite(int32(len(c.stringArg())))
+	case "GetStringChars", "GetStringCritical":
+		return c.pinString(true)
+	case "GetStringUTFChars":
+		return c.pinString(false)
+	case "ReleaseStringChars", "ReleaseStringUTFChars", "ReleaseStringCritical":
+		var str, chars uintptr
+		if err := c.args.Extract(&str, &chars); err != nil {
+			return err
+		}
+		v.unpin(uint64(chars))
+		return nil
+	case "GetStringRegion", "GetStringUTFRegion":
+		return c.stringRegion(index == envRegisterNatives+5)
+	case "GetArrayLength":
+		obj := v.object(c.ref())
+		if obj == nil {
+			return ErrReferenceInvalid
+		}
+		return c.ctx.RetWrite(int32(reflect.ValueOf(obj.Value()).Len()))
+	case "NewObjectArray":
+		return c.newObjectArray()
+	case "GetObjectArrayElement", "SetObjectArrayElement":
+		return c.objectArrayElement(index == envNewString+11)
+	case "GetPrimitiveArrayCritical":
+		return c.getArrayElements()
+	case "ReleasePrimitiveArrayCritical":
+		return c.releaseArrayElements()
+	case "RegisterNatives":
+		return c.registerNatives()
+	case "UnregisterNatives":
+		class, err := c.classArg()
+		if err != nil {
+			return err
+		}
+		class.unregisterNatives()
+		return c.ctx.RetWrite(int32(0))
+	case "GetJavaVM":
+		var pvm uintptr
+		if err := c.args.Extract(&pvm); err != nil {
+			return err
+		}
+		v.writeWord(c.ctx, uint64(pvm), v.vmAddr)
+		return c.ctx.RetWrite(int32(JNI_OK))
+	}
+	return ErrUnsupported
+}
+
+func (c *envCall) ref() uint64 {
+	var h uintptr
+	c.args.Extract(&h)
+	return uint64(h)
+}
+
+func (c *envCall) classArg() (*class, error) {
+	class := c.vm.class(c.ref())
+	if class == nil {
+		return nil, ErrReferenceInvalid
+	}
+	return class, nil
+}
+
+func (c *envCall) stringArg() string {
+	obj := c.vm.object(c.ref())
+	if obj == nil {
+		return ""
+	}
+	s, _ := obj.Value().(string)
+	return s
+}
+
+func (c *envCall) retObject(obj Object) error {
+	return c.ctx.RetWrite(uintptr(c.vm.ref(obj)))
+}
+
+func (c *envCall) retBool(b bool) error {
+	if b {
+		return c.ctx.RetWrite(uint8(1))
+	}
+	return c.ctx.RetWrite(uint8(0))
+}
+
+func (v *vm) writeWord(ctx debugger.Context, addr, value uint64) error {
+	buf := make([]byte, v.dbg.PointerSize())
+	if len(buf) == 4 {
+		binary.LittleEndian.PutUint32(buf, uint32(value))
+	} else {
+		binary.LittleEndian.PutUint64(buf, value)
+	}
+	return ctx.ToPointer(addr).MemWrite(buf)
+}
+
+func (c *envCall) reader(variant int) (argReader, error) {
+	switch variant {
+	case 1:
+		var va uintptr
+		if err := c.args.Extract(&va); err != nil {
+			return nil, err
+		}
+		return newVaList(c.vm.dbg, uint64(va))
+	case 2:
+		var ptr uintptr
+		if err := c.args.Extract(&ptr); err != nil {
+			return nil, err
+		}
+		return &jvalues{ptr: c.ctx.ToPointer(uint64(ptr)), ws: c.vm.dbg.PointerSize()}, nil
+	}
+	return &varArgs{args: c.args}, nil
+}
+
+func (c *envCall) readArgs(variant int, params []string) ([]any, error) {
+	r, err := c.reader(variant)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, len(params))
+	for i, param := range params {
+		raw, err := r.read(param[0])
+		if err != nil {
+			return nil, err
+		}
+		if isReference(param[0]) {
+			args[i] = c.vm.object(raw)
+		} else {
+			args[i] = fromRaw(param[0], raw)
+		}
+	}
+	return args, nil
+}
+
+func (c *envCall) value(typ byte) (any, error) {
+	switch typ {
+	case 'F':
+		var v float32
+		err := c.args.Extract(&v)
+		return v, err
+	case 'D':
+		var v float64
+		err := c.args.Extract(&v)
+		return v, err
+	case 'J':
+		var v int64
+		err := c.args.Extract(&v)
+		return v, err
+	case 'L', '[':
+		return c.vm.object(c.ref()), nil
+	}
+	var v int32
+	err := c.args.Extract(&v)
+	return fromRaw(typ, uint64(v)), err
+}
+
+func (c *envCall) ret(sig string, value any) error {
+	typ := sig[0]
+	if typ == 'V' {
+		return nil
+	} else if isReference(typ) {
+		return c.retObject(c.vm.toObject(sig, value))
+	}
+	return c.ctx.RetWrite(typed(typ, toRaw(typ, value)))
+}
+
+func (c *envCall) callMethod(off int, nonvirtual, static bool) error {
+	v := c.vm
+	var this Object
+	var target *class
+	if static {
+		class, err := c.classArg()
+		if err != nil {
+			return err
+		}
+		target = class
+	} else {
+		this = v.object(c.ref())
+		if this == nil {
+			return ErrReferenceInvalid
+		}
+		target, _ = this.Class().(*class)
+		if nonvirtual {
+			class, err := c.classArg()
+			if err != nil {
+				return err
+			}
+			target = class
+		}
+	}
+	id := v.method(c.ref())
+	if id == nil {
+		return ErrReferenceInvalid
+	}
+	args, err := c.readArgs(off%3, id.parsed.params)
+	if err != nil {
+		return err
+	}
+	result, err := v.invoke(target, id, this, args)
+	if err != nil {
+		v.raise(err)
+		result = nil
+	}
+	return c.ret(id.parsed.ret, result)
+}
+
+func (c *envCall) newObject(name string) error {
+	v := c.vm
+	class, err := c.classArg()
+	if err != nil {
+		return err
+	}
+	id := v.method(c.ref())
+	if id == nil {
+		return ErrReferenceInvalid
+	}
+	variant := 0
+	switch name {
+	case "NewObjectV":
+		variant = 1
+	case "NewObjectA":
+		variant = 2
+	}
+	args, err := c.readArgs(variant, id.parsed.params)
+	if err != nil {
+		return err
+	}
+	obj := v.NewObject(class, nil)
+	if method := class.method(id.name, id.sig, false); method != nil {
+		if _, err = method(v, obj, args); err != nil {
+			v.raise(err)
+			return c.retObject(nil)
+		}
+	}
+	return c.retObject(obj)
+}
+
+func (c *envCall) getMethodID(static bool) error {
+	class, err := c.classArg()
+	if err != nil {
+		return err
+	}
+	var name, sig string
+	if err = c.args.Extract(&name, &sig); err != nil {
+		return err
+	}
+	id, err := c.vm.methodID(class, name, sig, static)
+	if err != nil {
+		c.vm.throwNew("java/lang/NoSuchMethodError", name+sig)
+	}
+	return c.ctx.RetWrite(uintptr(id))
+}
+
+func (c *envCall) getFieldID(static bool) error {
+	class, err := c.classArg()
+	if err != nil {
+		return err
+	}
+	var name, sig string
+	if err = c.args.Extract(&name, &sig); err != nil {
+		return err
+	}
+	return c.ctx.RetWrite(uintptr(c.vm.fieldID(class, name, sig, static)))
+}
+
+func (c *envCall) field() (Object, *fieldID, error) {
+	obj := c.vm.object(c.ref())
+	if obj == nil {
+		return nil, nil, ErrReferenceInvalid
+	}
+	id := c.vm.field(c.ref())
+	if id == nil {
+		return nil, nil, ErrReferenceInvalid
+	}
+	return obj, id, nil
+}
+
+func (c *envCall) getField() error {
+	obj, id, err := c.field()
+	if err != nil {
+		return err
+	}
+	return c.ret(id.sig, obj.Field(id.name))
+}
+
+func (c *envCall) setField(typ byte) error {
+	obj, id, err := c.field()
+	if err != nil {
+		return err
+	}
+	value, err := c.value(typ)
+	if err != nil {
+		return err
+	}
+	obj.SetField(id.name, value)
+	return nil
+}
+
+func (c *envCall) array() (Object, reflect.Value, error) {
+	obj := c.vm.object(c.ref())
+	if obj == nil {
+		return nil, reflect.Value{}, ErrReferenceInvalid
+	}
+	rv := reflect.ValueOf(obj.Value())
+	if rv.Kind() != reflect.Slice {
+		return nil, reflect.Value{}, ErrReferenceInvalid
+	}
+	return obj, rv, nil
+}
+
+func (c *envCall) newArray(typ byte) error {
+	var n int32
+	if err := c.args.Extract(&n); err != nil {
+		return err
+	} else if n < 0 {
+		c.vm.throwNew("java/lang/NegativeArraySizeException", "")
+		return c.retObject(nil)
+	}
+	return c.retObject(c.vm.NewArray("["+string(typ), newSlice(typ, int(n))))
+}
+
+func (c *envCall) newObjectArray() error {
+	var n int32
+	if err := c.args.Extract(&n); err != nil {
+		return err
+	}
+	class, err := c.classArg()
+	if err != nil {
+		return err
+	}
+	init := c.vm.object(c.ref())
+	if n < 0 {
+		c.vm.throwNew("java/lang/NegativeArraySizeException", "")
+		return c.retObject(nil)
+	}
+	elems := make([]Object, n)
+	for i := range elems {
+		elems[i] = init
+	}
+	sig := "[L" + class.name + ";"
+	if class.name[0] == '[' {
+		sig = "[" + class.name
+	}
+	return c.retObject(c.vm.NewArray(sig, elems))
+}
+
+func (c *envCall) objectArrayElement(set bool) error {
+	obj, _, err := c.array()
+	if err != nil {
+		return err
+	}
+	elems, ok := obj.Value().([]Object)
+	if !ok {
+		return ErrReferenceInvalid
+	}
+	var index int32
+	if err = c.args.Extract(&index); err != nil {
+		return err
+	} else if index < 0 || int(index) >= len(elems) {
+		c.vm.throwNew("java/lang/ArrayIndexOutOfBoundsException", fmt.Sprint(index))
+		if set {
+			return nil
+		}
+		return c.retObject(nil)
+	}
+	if set {
+		elems[index] = c.vm.object(c.ref())
+		return nil
+	}
+	return c.retObject(elems[index])
+}
+
+func (c *envCall) getArrayElements() error {
+	obj, rv, err := c.array()
+	if err != nil {
+		return err
+	}
+	var isCopy uintptr
+	if err = c.args.Extract(&isCopy); err != nil {
+		return err
+	}
+	data, err := binary.Append(nil, binary.LittleEndian, rv.Interface())
+	if err != nil {
+		return err
+	}
+	addr, err := c.vm.pin(c.ctx, obj, data, uint64(isCopy))
+	if err != nil {
+		return err
+	}
+	return c.ctx.RetWrite(uintptr(addr))
+}
+
+func (c *envCall) releaseArrayElements() error {
+	_, rv, err := c.array()
+	if err != nil {
+		return err
+	}
+	var elems uintptr
+	var mode int32
+	if err = c.args.Extract(&elems, &mode); err != nil {
+		return err
+	}
+	if mode != JNI_ABORT {
+		size := binary.Size(rv.Interface())
+		buf, err := c.ctx.ToPointer(uint64(elems)).MemRead(uint64(size))
+		if err != nil {
+			return err
+		}
+		if _, err = binary.Decode(buf, binary.LittleEndian, rv.Interface()); err != nil {
+			return err
+		}
+	}
+	if mode != JNI_COMMIT {
+		return c.vm.unpin(uint64(elems))
+	}
+	return nil
+}
+
+func (c *envCall) arrayRegion(set bool) error {
+	_, rv, err := c.array()
+	if err != nil {
+		return err
+	}
+	var start, n int32
+	var buf uintptr
+	if err = c.args.Extract(&start, &n, &buf); err != nil {
+		return err
+	} else if start < 0 || n < 0 || int(start)+int(n) > rv.Len() {
+		c.vm.throwNew("java/lang/ArrayIndexOutOfBoundsException", fmt.Sprint(start))
+		return nil
+	}
+	region := rv.Slice(int(start), int(start+n)).Interface()
+	ptr := c.ctx.ToPointer(uint64(buf))
+	if set {
+		data, err := ptr.MemRead(uint64(binary.Size(region)))
+		if err != nil {
+			return err
+		}
+		_, err = binary.Decode(data, binary.LittleEndian, region)
+		return err
+	}
+	data, err := binary.Append(nil, binary.LittleEndian, region)
+	if err != nil {
+		return err
+	}
+	return ptr.MemWrite(data)
+}
+
+func (c *envCall) pinString(wide bool) error {
+	obj := c.vm.object(c.ref())
+	if obj == nil {
+		return ErrReferenceInvalid
+	}
+	var isCopy uintptr
+	if err := c.args.Extract(&isCopy); err != nil {
+		return err
+	}
+	s, _ := obj.Value().(string)
+	var data []byte
+	if wide {
+		data, _ = binary.Append(nil, binary.LittleEndian, utf16.Encode([]rune(s)))
+		data = append(data, 0, 0)
+	} else {
+		data = append([]byte(s), 0)
+	}
+	addr, err := c.vm.pin(c.ctx, obj, data, uint64(isCopy))
+	if err != nil {
+		return err
+	}
+	return c.ctx.RetWrite(uintptr(addr))
+}
+
+func (c *envCall) stringRegion(wide bool) error {
+	chars := utf16.Encode([]rune(c.stringArg()))
+	var start, n int32
+	var buf uintptr
+	if err := c.args.Extract(&start, &n, &buf); err != nil {
+		return err
+	} else if start < 0 || n < 0 || int(start)+int(n) > len(chars) {
+		c.vm.throwNew("java/lang/StringIndexOutOfBoundsException", fmt.Sprint(start))
+		return nil
+	}
+	region := chars[start : start+n]
+	var data []byte
+	if wide {
+		data, _ = binary.Append(nil, binary.LittleEndian, region)
+	} else {
+		data = []byte(string(utf16.Decode(region)))
+	}
+	return c.ctx.ToPointer(uint64(buf)).MemWrite(data)
+}
+
+func (c *envCall) registerNatives() error {
+	class, err := c.classArg()
+	if err != nil {
+		return err
+	}
+	var methods uintptr
+	var n int32
+	if err = c.args.Extract(&methods, &n); err != nil {
+		return err
+	}
+	ws := c.vm.dbg.PointerSize()
+	ptr := c.ctx.ToPointer(uint64(methods))
+	for i := range uint64(max(n, 0)) {
+		entry := ptr.Add(i * 3 * ws)
+		var words [3]uint64
+		for j := range words {
+			if words[j], err = readWord(entry.Add(uint64(j)*ws), ws); err != nil {
+				return err
+			}
+		}
+		name, err := c.ctx.ToPointer(words[0]).MemReadString()
+		if err != nil {
+			return err
+		}
+		sig, err := c.ctx.ToPointer(words[1]).MemReadString()
+		if err != nil {
+			return err
+		}
+		class.registerNative(name, sig, words[2])
+	}
+	return c.ctx.RetWrite(int32(JNI_OK))
+}
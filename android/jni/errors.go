@@ -0,0 +1,10 @@
+package jni
+
+import "errors"
+
+var (
+	ErrUnsupported      = errors.New("jni function unsupported")
+	ErrSignatureInvalid = errors.New("jni signature invalid")
+	ErrReferenceInvalid = errors.New("jni reference invalid")
+	ErrNativeNotFound   = errors.New("jni native method not found")
+)
@@ -0,0 +1,367 @@
+package jni
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	JNI_VERSION_1_6 = 0x00010006
+
+	JNI_OK     = 0
+	JNI_ERR    = -1
+	JNI_COMMIT = 1
+	JNI_ABORT  = 2
+
+	handleBase = 0x1000
+	handleStep = 4
+)
+
+type VM interface {
+	io.Closer
+	Debugger() debugger.Debugger
+	EnvAddr() uint64
+	VMAddr() uint64
+	DefineClass(name string, super Class) Class
+	FindClass(name string) (Class, bool)
+	NewObject(class Class, value any) Object
+	NewString(s string) Object
+	NewArray(sig string, elems any) Object
+	Throw(obj Object)
+	ExceptionClear() Object
+	OnLoad(ctx context.Context, module debugger.Module) (int32, error)
+	Call(ctx context.Context, module debugger.Module, class Class, name, sig string, this Object, args ...any) (any, error)
+}
+
+type Exception struct {
+	Object Object
+}
+
+type methodID struct {
+	class  *class
+	name   string
+	sig    string
+	static bool
+	parsed signature
+}
+
+type fieldID struct {
+	name   string
+	sig    string
+	static bool
+}
+
+type vm struct {
+	mu        sync.RWMutex
+	dbg       debugger.Debugger
+	region    emulator.MemRegion
+	envAddr   uint64
+	vmAddr    uint64
+	ctrls     []debugger.ControlHandler
+	classes   map[string]*class
+	handles   map[uint64]Object
+	refs      map[Object]uint64
+	next      uint64
+	methods   map[uint64]*methodID
+	fields    map[uint64]*fieldID
+	ids       map[string]uint64
+	exception Object
+	pinned    map[uint64]Object
+}
+
+func New(dbg debugger.Debugger) (VM, error) {
+	v := &vm{
+		dbg:     dbg,
+		classes: make(map[string]*class),
+		handles: make(map[uint64]Object),
+		refs:    make(map[Object]uint64),
+		next:    handleBase,
+		methods: make(map[uint64]*methodID),
+		fields:  make(map[uint64]*fieldID),
+		ids:     make(map[string]uint64),
+		pinned:  make(map[uint64]Object),
+	}
+	object := v.DefineClass("java/lang/Object", nil)
+	v.classes["java/lang/Class"] = v.newClass("java/lang/Class", object)
+	for _, c := range v.classes {
+		c.class = v.classes["java/lang/Class"]
+	}
+	v.DefineClass("java/lang/String", object)
+	throwable := v.DefineClass("java/lang/Throwable", object)
+	exception := v.DefineClass("java/lang/Exception", throwable)
+	runtime := v.DefineClass("java/lang/RuntimeException", exception)
+	v.DefineClass("java/lang/NoSuchMethodError", throwable)
+	v.DefineClass("java/lang/NoClassDefFoundError", throwable)
+	v.DefineClass("java/lang/NullPointerException", runtime)
+	err := v.buildTables()
+	if err != nil {
+		v.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *vm) Close() error {
+	for _, ctrl := range v.ctrls {
+		ctrl.Close()
+	}
+	v.ctrls = nil
+	for addr := range v.pinned {
+		v.dbg.MemFree(addr)
+	}
+	clear(v.pinned)
+	if v.region.Size == 0 {
+		return nil
+	}
+	err := v.dbg.MapFree(v.region.Addr, v.region.Size)
+	v.region = emulator.MemRegion{}
+	return err
+}
+
+func (v *vm) Debugger() debugger.Debugger {
+	return v.dbg
+}
+
+func (v *vm) EnvAddr() uint64 {
+	return v.envAddr
+}
+
+func (v *vm) VMAddr() uint64 {
+	return v.vmAddr
+}
+
+func (v *vm) DefineClass(name string, super Class) Class {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.classes[name]; ok {
+		return c
+	}
+	if super == nil && name != "java/lang/Object" {
+		super = v.classes["java/lang/Object"]
+	}
+	c := v.newClass(name, super)
+	c.class = v.classes["java/lang/Class"]
+	v.classes[name] = c
+	return c
+}
+
+func (v *vm) FindClass(name string) (Class, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	c, ok := v.classes[name]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func (v *vm) NewObject(class Class, value any) Object {
+	return &object{class: class, value: value}
+}
+
+func (v *vm) NewString(s string) Object {
+	class, _ := v.FindClass("java/lang/String")
+	return v.NewObject(class, s)
+}
+
+func (v *vm) NewArray(sig string, elems any) Object {
+	return v.NewObject(v.DefineClass(sig, nil), elems)
+}
+
+func (v *vm) Throw(obj Object) {
+	v.mu.Lock()
+	v.exception = obj
+	v.mu.Unlock()
+}
+
+func (v *vm) ExceptionClear() Object {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	obj := v.exception
+	v.exception = nil
+	return obj
+}
+
+func (v *vm) newClass(name string, super Class) *class {
+	return &class{
+		name:    name,
+		super:   super,
+		methods: make(map[string]Method),
+		statics: make(map[string]Method),
+		natives: make(map[string]uint64),
+	}
+}
+
+func (v *vm) buildTables() error {
+	ws := v.dbg.PointerSize()
+	size := (uint64(len(envFunctions)) + uint64(len(vmFunctions)) + 2) * ws
+	region, err := v.dbg.MapAlloc(debugger.Align(size, v.dbg.Emulator().PageSize()), emulator.MEM_PROT_READ)
+	if err != nil {
+		return err
+	}
+	v.region = region
+	words := make([]uint64, 0, size/ws)
+	envTable := region.Addr + 2*ws
+	vmTable := envTable + uint64(len(envFunctions))*ws
+	words = append(words, envTable, vmTable)
+	for i, name := range envFunctions {
+		var addr uint64
+		if name != "" {
+			ctrl, err := v.dbg.AddControl(v.handleEnv, i)
+			if err != nil {
+				return err
+			}
+			v.ctrls = append(v.ctrls, ctrl)
+			addr = ctrl.Addr()
+		}
+		words = append(words, addr)
+	}
+	for i, name := range vmFunctions {
+		var addr uint64
+		if name != "" {
+			ctrl, err := v.dbg.AddControl(v.handleVM, i)
+			if err != nil {
+				return err
+			}
+			v.ctrls = append(v.ctrls, ctrl)
+			addr = ctrl.Addr()
+		}
+		words = append(words, addr)
+	}
+	buf := make([]byte, 0, size)
+	for _, word := range words {
+		if ws == 4 {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(word))
+		} else {
+			buf = binary.LittleEndian.AppendUint64(buf, word)
+		}
+	}
+	v.envAddr, v.vmAddr = region.Addr, region.Addr+ws
+	return v.dbg.Emulator().MemWrite(region.Addr, buf)
+}
+
+func (v *vm) ref(obj Object) uint64 {
+	if obj == nil {
+		return 0
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok := v.refs[obj]; ok {
+		return h
+	}
+	h := v.next
+	v.next += handleStep
+	v.handles[h] = obj
+	v.refs[obj] = h
+	return h
+}
+
+func (v *vm) object(h uint64) Object {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.handles[h]
+}
+
+func (v *vm) class(h uint64) *class {
+	c, _ := v.object(h).(*class)
+	return c
+}
+
+func (v *vm) method(id uint64) *methodID {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.methods[id]
+}
+
+func (v *vm) field(id uint64) *fieldID {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.fields[id]
+}
+
+func (v *vm) pin(ctx debugger.Context, obj Object, data []byte, isCopy uint64) (uint64, error) {
+	addr, err := v.dbg.MemAlloc(max(uint64(len(data)), 1))
+	if err != nil {
+		return 0, err
+	}
+	if err = ctx.ToPointer(addr).MemWrite(data); err != nil {
+		v.dbg.MemFree(addr)
+		return 0, err
+	}
+	if isCopy != 0 {
+		ctx.ToPointer(isCopy).MemWrite([]byte{1})
+	}
+	v.mu.Lock()
+	v.pinned[addr] = obj
+	v.mu.Unlock()
+	return addr, nil
+}
+
+func (v *vm) unpin(addr uint64) error {
+	v.mu.Lock()
+	_, ok := v.pinned[addr]
+	delete(v.pinned, addr)
+	v.mu.Unlock()
+	if !ok {
+		return ErrReferenceInvalid
+	}
+	return v.dbg.MemFree(addr)
+}
+
+func (v *vm) methodID(c *class, name, sig string, static bool) (uint64, error) {
+	parsed, err := parseSignature(sig)
+	if err != nil {
+		return 0, err
+	}
+	key := c.name + "." + name + sig
+	if static {
+		key = "static " + key
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if id, ok := v.ids[key]; ok {
+		return id, nil
+	}
+	id := v.next
+	v.next += handleStep
+	v.methods[id] = &methodID{class: c, name: name, sig: sig, static: static, parsed: parsed}
+	v.ids[key] = id
+	return id, nil
+}
+
+func (v *vm) fieldID(c *class, name, sig string, static bool) uint64 {
+	key := c.name + ":" + name + ":" + sig
+	if static {
+		key = "static " + key
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if id, ok := v.ids[key]; ok {
+		return id
+	}
+	id := v.next
+	v.next += handleStep
+	v.fields[id] = &fieldID{name: name, sig: sig, static: static}
+	v.ids[key] = id
+	return id
+}
+
+func (v *vm) throwNew(name string, msg string) {
+	class := v.DefineClass(name, nil)
+	v.Throw(v.NewObject(class, msg))
+}
+
+func (e *Exception) Error() string {
+	if e.Object == nil {
+		return "java exception"
+	}
+	if msg, ok := e.Object.Value().(string); ok && msg != "" {
+		return e.Object.Class().Name() + ": " + msg
+	}
+	return e.Object.Class().Name()
+}
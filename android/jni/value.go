@@ -0,0 +1,386 @@
+package jni
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type signature struct {
+	params []string
+	ret    string
+}
+
+type argReader interface {
+	read(typ byte) (uint64, error)
+}
+
+type varArgs struct {
+	args debugger.Args
+}
+
+type jvalues struct {
+	ptr emulator.Pointer
+	ws  uint64
+	off uint64
+}
+
+type vaList32 struct {
+	ptr   emulator.Pointer
+	off   uint64
+	align uint64
+}
+
+type vaList64 struct {
+	dbg   debugger.Debugger
+	stack uint64
+	grTop uint64
+	vrTop uint64
+	grOff int32
+	vrOff int32
+}
+
+type vaListSysV struct {
+	dbg      debugger.Debugger
+	gpOff    uint32
+	fpOff    uint32
+	overflow uint64
+	regSave  uint64
+}
+
+func parseSignature(sig string) (signature, error) {
+	var s signature
+	if len(sig) < 3 || sig[0] != '(' {
+		return s, ErrSignatureInvalid
+	}
+	i := 1
+	for i < len(sig) && sig[i] != ')' {
+		n := typeLen(sig[i:])
+		if n == 0 {
+			return s, ErrSignatureInvalid
+		}
+		s.params = append(s.params, sig[i:i+n])
+		i += n
+	}
+	if i >= len(sig) {
+		return s, ErrSignatureInvalid
+	}
+	s.ret = sig[i+1:]
+	if s.ret != "V" && typeLen(s.ret) != len(s.ret) {
+		return s, ErrSignatureInvalid
+	}
+	return s, nil
+}
+
+func typeLen(sig string) int {
+	if sig == "" {
+		return 0
+	}
+	switch sig[0] {
+	case 'Z', 'B', 'C', 'S', 'I', 'J', 'F', 'D':
+		return 1
+	case 'L':
+		if i := strings.IndexByte(sig, ';'); i > 0 {
+			return i + 1
+		}
+	case '[':
+		if n := typeLen(sig[1:]); n > 0 {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+func isReference(typ byte) bool {
+	return typ == 'L' || typ == '['
+}
+
+func elemSize(typ byte) uint64 {
+	switch typ {
+	case 'Z', 'B':
+		return 1
+	case 'C', 'S':
+		return 2
+	case 'I', 'F':
+		return 4
+	}
+	return 8
+}
+
+func newSlice(typ byte, n int) any {
+	switch typ {
+	case 'Z':
+		return make([]bool, n)
+	case 'B':
+		return make([]byte, n)
+	case 'C':
+		return make([]uint16, n)
+	case 'S':
+		return make([]int16, n)
+	case 'I':
+		return make([]int32, n)
+	case 'J':
+		return make([]int64, n)
+	case 'F':
+		return make([]float32, n)
+	case 'D':
+		return make([]float64, n)
+	}
+	return make([]Object, n)
+}
+
+func toRaw(typ byte, v any) uint64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case float32:
+		if typ == 'D' {
+			return math.Float64bits(float64(v))
+		}
+		return uint64(math.Float32bits(v))
+	case float64:
+		if typ == 'F' {
+			return uint64(math.Float32bits(float32(v)))
+		}
+		return math.Float64bits(v)
+	}
+	rv := reflect.ValueOf(v)
+	switch typ {
+	case 'F':
+		if rv.CanConvert(reflect.TypeFor[float32]()) {
+			return uint64(math.Float32bits(rv.Convert(reflect.TypeFor[float32]()).Interface().(float32)))
+		}
+	case 'D':
+		if rv.CanConvert(reflect.TypeFor[float64]()) {
+			return math.Float64bits(rv.Convert(reflect.TypeFor[float64]()).Interface().(float64))
+		}
+	default:
+		switch {
+		case rv.CanInt():
+			return uint64(rv.Int())
+		case rv.CanUint():
+			return rv.Uint()
+		}
+	}
+	return 0
+}
+
+func fromRaw(typ byte, raw uint64) any {
+	switch typ {
+	case 'Z':
+		return raw&0xff != 0
+	case 'B':
+		return int8(raw)
+	case 'C':
+		return uint16(raw)
+	case 'S':
+		return int16(raw)
+	case 'I':
+		return int32(raw)
+	case 'J':
+		return int64(raw)
+	case 'F':
+		return math.Float32frombits(uint32(raw))
+	case 'D':
+		return math.Float64frombits(raw)
+	}
+	return raw
+}
+
+func typed(typ byte, raw uint64) any {
+	switch typ {
+	case 'Z':
+		return uint8(raw)
+	case 'B':
+		return int8(raw)
+	case 'C':
+		return uint16(raw)
+	case 'S':
+		return int16(raw)
+	case 'I':
+		return int32(raw)
+	case 'J':
+		return int64(raw)
+	case 'F':
+		return math.Float32frombits(uint32(raw))
+	case 'D':
+		return math.Float64frombits(raw)
+	}
+	return uintptr(raw)
+}
+
+func (va *varArgs) read(typ byte) (uint64, error) {
+	switch typ {
+	case 'J':
+		var v int64
+		err := va.args.Extract(&v)
+		return uint64(v), err
+	case 'F':
+		var v float64
+		err := va.args.Extract(&v)
+		return uint64(math.Float32bits(float32(v))), err
+	case 'D':
+		var v float64
+		err := va.args.Extract(&v)
+		return math.Float64bits(v), err
+	case 'L', '[':
+		var v uintptr
+		err := va.args.Extract(&v)
+		return uint64(v), err
+	}
+	var v int32
+	err := va.args.Extract(&v)
+	return uint64(v), err
+}
+
+func (jv *jvalues) read(typ byte) (uint64, error) {
+	buf, err := jv.ptr.Add(jv.off).MemRead(8)
+	jv.off += 8
+	if err != nil {
+		return 0, err
+	}
+	size := elemSize(typ)
+	if isReference(typ) {
+		size = jv.ws
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(buf)), nil
+	case 4:
+		if typ == 'I' {
+			return uint64(int32(binary.LittleEndian.Uint32(buf))), nil
+		}
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func (va *vaList32) read(typ byte) (uint64, error) {
+	if typ == 'J' || typ == 'D' || typ == 'F' {
+		va.off = (va.off + va.align - 1) &^ (va.align - 1)
+		v, err := readWord(va.ptr.Add(va.off), 8)
+		va.off += 8
+		if typ == 'F' {
+			v = uint64(math.Float32bits(float32(math.Float64frombits(v))))
+		}
+		return v, err
+	}
+	v, err := readWord(va.ptr.Add(va.off), 4)
+	va.off += 4
+	if isReference(typ) {
+		return v, err
+	}
+	return uint64(int32(v)), err
+}
+
+func newVaList64(dbg debugger.Debugger, addr uint64) (*vaList64, error) {
+	raw, err := dbg.ToPointer(addr).MemRead(32)
+	if err != nil {
+		return nil, err
+	}
+	return &vaList64{
+		dbg:   dbg,
+		stack: binary.LittleEndian.Uint64(raw[0:]),
+		grTop: binary.LittleEndian.Uint64(raw[8:]),
+		vrTop: binary.LittleEndian.Uint64(raw[16:]),
+		grOff: int32(binary.LittleEndian.Uint32(raw[24:])),
+		vrOff: int32(binary.LittleEndian.Uint32(raw[28:])),
+	}, nil
+}
+
+func (va *vaList64) read(typ byte) (uint64, error) {
+	var addr uint64
+	if typ == 'F' || typ == 'D' {
+		if va.vrOff < 0 {
+			addr = va.vrTop + uint64(int64(va.vrOff))
+			va.vrOff += 16
+		}
+	} else if va.grOff < 0 {
+		addr = va.grTop + uint64(int64(va.grOff))
+		va.grOff += 8
+	}
+	if addr == 0 {
+		addr = va.stack
+		va.stack += 8
+	}
+	v, err := readWord(va.dbg.ToPointer(addr), 8)
+	switch typ {
+	case 'F':
+		v = uint64(math.Float32bits(float32(math.Float64frombits(v))))
+	case 'Z', 'B', 'C', 'S', 'I':
+		v = uint64(int32(v))
+	}
+	return v, err
+}
+
+func newVaListSysV(dbg debugger.Debugger, addr uint64) (*vaListSysV, error) {
+	raw, err := dbg.ToPointer(addr).MemRead(24)
+	if err != nil {
+		return nil, err
+	}
+	return &vaListSysV{
+		dbg:      dbg,
+		gpOff:    binary.LittleEndian.Uint32(raw[0:]),
+		fpOff:    binary.LittleEndian.Uint32(raw[4:]),
+		overflow: binary.LittleEndian.Uint64(raw[8:]),
+		regSave:  binary.LittleEndian.Uint64(raw[16:]),
+	}, nil
+}
+
+func (va *vaListSysV) read(typ byte) (uint64, error) {
+	var addr uint64
+	if typ == 'F' || typ == 'D' {
+		if va.fpOff < 176 {
+			addr = va.regSave + uint64(va.fpOff)
+			va.fpOff += 16
+		}
+	} else if va.gpOff < 48 {
+		addr = va.regSave + uint64(va.gpOff)
+		va.gpOff += 8
+	}
+	if addr == 0 {
+		addr = va.overflow
+		va.overflow += 8
+	}
+	v, err := readWord(va.dbg.ToPointer(addr), 8)
+	switch typ {
+	case 'F':
+		v = uint64(math.Float32bits(float32(math.Float64frombits(v))))
+	case 'Z', 'B', 'C', 'S', 'I':
+		v = uint64(int32(v))
+	}
+	return v, err
+}
+
+func newVaList(dbg debugger.Debugger, addr uint64) (argReader, error) {
+	switch dbg.Arch() {
+	case emulator.ARCH_ARM64:
+		return newVaList64(dbg, addr)
+	case emulator.ARCH_X86_64:
+		return newVaListSysV(dbg, addr)
+	case emulator.ARCH_X86:
+		return &vaList32{ptr: dbg.ToPointer(addr), align: 4}, nil
+	}
+	return &vaList32{ptr: dbg.ToPointer(addr), align: 8}, nil
+}
+
+func readWord(ptr emulator.Pointer, size uint64) (uint64, error) {
+	buf, err := ptr.MemRead(size)
+	if err != nil {
+		return 0, err
+	} else if size == 4 {
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
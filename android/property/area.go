@@ -0,0 +1,203 @@
+package property
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	PROP_VALUE_MAX = 92
+
+	areaSize    = 128 * 1024
+	areaMagic   = 0x504f5250
+	areaVersion = 0xfc6ed0ab
+	headerSize  = 128
+	btSize      = 20
+	infoSize    = 4 + PROP_VALUE_MAX
+)
+
+type area struct {
+	data   []byte
+	lo, hi int
+}
+
+func newArea() *area {
+	a := &area{data: make([]byte, areaSize)}
+	binary.LittleEndian.PutUint32(a.data[8:], areaMagic)
+	binary.LittleEndian.PutUint32(a.data[12:], areaVersion)
+	a.setUsed(0)
+	a.alloc(btSize)
+	a.lo, a.hi = 0, len(a.data)
+	return a
+}
+
+func (a *area) find(name string) (uint32, bool) {
+	bt, ok := a.trie(name, false)
+	if !ok {
+		return 0, false
+	}
+	prop := a.u32(bt + 4)
+	return prop, prop != 0
+}
+
+func (a *area) set(name, value string) error {
+	if len(value) >= PROP_VALUE_MAX {
+		value = value[:PROP_VALUE_MAX-1]
+	}
+	bt, ok := a.trie(name, true)
+	if !ok {
+		return ErrAreaFull
+	}
+	prop := a.u32(bt + 4)
+	if prop == 0 {
+		prop, ok = a.alloc(infoSize + uint32(len(name)) + 1)
+		if !ok {
+			return ErrAreaFull
+		}
+		a.putU32(prop, uint32(len(value))<<24)
+		a.putString(prop+4, value, PROP_VALUE_MAX)
+		a.putString(prop+infoSize, name, len(name)+1)
+		a.putU32(bt+4, prop)
+	} else {
+		serial := a.u32(prop)
+		a.putString(prop+4, value, PROP_VALUE_MAX)
+		a.putU32(prop, uint32(len(value))<<24|(serial+2)&0xffffff)
+	}
+	a.touch(4, 8)
+	binary.LittleEndian.PutUint32(a.data[4:], binary.LittleEndian.Uint32(a.data[4:])+1)
+	return nil
+}
+
+func (a *area) trie(name string, create bool) (uint32, bool) {
+	if name == "" {
+		return 0, false
+	}
+	current := uint32(0)
+	for _, seg := range strings.Split(name, ".") {
+		if seg == "" {
+			return 0, false
+		}
+		children := a.u32(current + 16)
+		if children == 0 {
+			if !create {
+				return 0, false
+			}
+			node, ok := a.newNode(seg)
+			if !ok {
+				return 0, false
+			}
+			a.putU32(current+16, node)
+			current = node
+			continue
+		}
+		node, ok := a.findNode(children, seg, create)
+		if !ok {
+			return 0, false
+		}
+		current = node
+	}
+	return current, true
+}
+
+func (a *area) findNode(node uint32, name string, create bool) (uint32, bool) {
+	for {
+		var link uint32
+		switch c := compare(name, a.nodeName(node)); {
+		case c == 0:
+			return node, true
+		case c < 0:
+			link = node + 8
+		default:
+			link = node + 12
+		}
+		next := a.u32(link)
+		if next == 0 {
+			if !create {
+				return 0, false
+			}
+			next, ok := a.newNode(name)
+			if !ok {
+				return 0, false
+			}
+			a.putU32(link, next)
+			return next, true
+		}
+		node = next
+	}
+}
+
+func (a *area) newNode(name string) (uint32, bool) {
+	node, ok := a.alloc(btSize + uint32(len(name)) + 1)
+	if !ok {
+		return 0, false
+	}
+	a.putU32(node, uint32(len(name)))
+	a.putString(node+btSize, name, len(name)+1)
+	return node, true
+}
+
+func (a *area) nodeName(node uint32) string {
+	n := a.u32(node)
+	off := headerSize + node + btSize
+	return string(a.data[off : off+n])
+}
+
+func (a *area) alloc(size uint32) (uint32, bool) {
+	size = (size + 3) &^ 3
+	used := a.used()
+	if headerSize+used+size > areaSize {
+		return 0, false
+	}
+	a.setUsed(used + size)
+	return used, true
+}
+
+func (a *area) used() uint32 {
+	return binary.LittleEndian.Uint32(a.data)
+}
+
+func (a *area) setUsed(n uint32) {
+	binary.LittleEndian.PutUint32(a.data, n)
+	a.touch(0, 4)
+}
+
+func (a *area) u32(off uint32) uint32 {
+	return binary.LittleEndian.Uint32(a.data[headerSize+off:])
+}
+
+func (a *area) putU32(off, v uint32) {
+	binary.LittleEndian.PutUint32(a.data[headerSize+off:], v)
+	a.touch(int(headerSize+off), int(headerSize+off)+4)
+}
+
+func (a *area) putString(off uint32, s string, size int) {
+	begin := int(headerSize + off)
+	clear(a.data[begin : begin+size])
+	copy(a.data[begin:], s)
+	a.touch(begin, begin+size)
+}
+
+func (a *area) touch(lo, hi int) {
+	if a.lo >= a.hi {
+		a.lo, a.hi = lo, hi
+		return
+	}
+	a.lo, a.hi = min(a.lo, lo), max(a.hi, hi)
+}
+
+func (a *area) dirty() (int, []byte) {
+	lo, hi := a.lo, a.hi
+	a.lo, a.hi = 0, 0
+	if lo >= hi {
+		return 0, nil
+	}
+	return lo, a.data[lo:hi]
+}
+
+func compare(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return bytes.Compare([]byte(a), []byte(b))
+}
@@ -0,0 +1,8 @@
+package property
+
+import "errors"
+
+var (
+	ErrAreaFull    = errors.New("property area full")
+	ErrNameInvalid = errors.New("property name invalid")
+)
@@ -0,0 +1,260 @@
+package property
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const DevicePath = "/dev/__properties__"
+
+type Store interface {
+	Get(name string) (string, bool)
+	Set(name, value string) error
+	Names() []string
+	Load(r io.Reader) error
+}
+
+type Service interface {
+	io.Closer
+	Store
+	debugger.FileHandler
+	AreaAddr() uint64
+}
+
+type service struct {
+	debugger.DefaultFileHandler
+	mu      sync.RWMutex
+	dbg     debugger.Debugger
+	values  map[string]string
+	area    *area
+	region  emulator.MemRegion
+	closers []io.Closer
+}
+
+type fileInfo struct {
+	modTime time.Time
+}
+
+type areaFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func New(dbg debugger.Debugger) (Service, error) {
+	s := &service{dbg: dbg, values: make(map[string]string), area: newArea()}
+	region, err := dbg.MapAlloc(areaSize, emulator.MEM_PROT_READ)
+	if err != nil {
+		return nil, err
+	}
+	s.region = region
+	err = s.flush()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	for name, callback := range map[string]debugger.CodeCallback{
+		"__system_property_get":  s.handleGet,
+		"__system_property_find": s.handleFind,
+		"__system_property_read": s.handleRead,
+	} {
+		hook, err := dbg.AddSymbolHook(name, emulator.HOOK_TYPE_CODE, callback, nil)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.closers = append(s.closers, hook)
+	}
+	dbg.AddFileHandler(s)
+	return s, nil
+}
+
+func (s *service) Close() error {
+	s.dbg.RemoveFileHandler(s)
+	for _, closer := range s.closers {
+		closer.Close()
+	}
+	s.closers = nil
+	if s.region.Size == 0 {
+		return nil
+	}
+	err := s.dbg.MapFree(s.region.Addr, s.region.Size)
+	s.region = emulator.MemRegion{}
+	return err
+}
+
+func (s *service) AreaAddr() uint64 {
+	return s.region.Addr
+}
+
+func (s *service) Get(name string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[name]
+	return value, ok
+}
+
+func (s *service) Set(name, value string) error {
+	if name == "" || strings.ContainsAny(name, "\x00 \t\r\n=") {
+		return ErrNameInvalid
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.area.set(name, value)
+	if err != nil {
+		return err
+	}
+	s.values[name] = value
+	return s.flush()
+}
+
+func (s *service) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Sorted(maps.Keys(s.values))
+}
+
+func (s *service) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || strings.HasPrefix(line, "import ") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		err := s.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *service) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	if name != DevicePath {
+		return nil, fs.ErrNotExist
+	} else if flag&(filesystem.O_WRONLY|filesystem.O_RDWR) != 0 {
+		return nil, fs.ErrPermission
+	}
+	s.mu.RLock()
+	data := slices.Clone(s.area.data)
+	s.mu.RUnlock()
+	return &areaFile{Reader: bytes.NewReader(data), info: fileInfo{modTime: s.dbg.Clock().Now()}}, nil
+}
+
+func (s *service) Stat(name string) (fs.FileInfo, error) {
+	if name != DevicePath {
+		return nil, fs.ErrNotExist
+	}
+	return &fileInfo{modTime: s.dbg.Clock().Now()}, nil
+}
+
+func (s *service) flush() error {
+	off, data := s.area.dirty()
+	if len(data) == 0 || s.region.Size == 0 {
+		return nil
+	}
+	return s.dbg.Emulator().MemWrite(s.region.Addr+uint64(off), data)
+}
+
+func (s *service) handleGet(ctx debugger.Context, addr, size uint64, data any) {
+	var name string
+	var value uintptr
+	err := ctx.ArgExtract(debugger.Calling_Default, &name, &value)
+	if err != nil {
+		return
+	}
+	v, _ := s.Get(name)
+	if len(v) >= PROP_VALUE_MAX {
+		v = v[:PROP_VALUE_MAX-1]
+	}
+	if value != 0 {
+		ctx.ToPointer(uint64(value)).MemWrite(append([]byte(v), 0))
+	}
+	ctx.RetWrite(int32(len(v)))
+	ctx.Return()
+}
+
+func (s *service) handleFind(ctx debugger.Context, addr, size uint64, data any) {
+	var name string
+	err := ctx.ArgExtract(debugger.Calling_Default, &name)
+	if err != nil {
+		return
+	}
+	s.mu.RLock()
+	prop, ok := s.area.find(name)
+	s.mu.RUnlock()
+	var pi uintptr
+	if ok {
+		pi = uintptr(s.region.Addr + headerSize + uint64(prop))
+	}
+	ctx.RetWrite(pi)
+	ctx.Return()
+}
+
+func (s *service) handleRead(ctx debugger.Context, addr, size uint64, data any) {
+	var pi, name, value uintptr
+	err := ctx.ArgExtract(debugger.Calling_Default, &pi, &name, &value)
+	if err != nil {
+		return
+	}
+	info, err := ctx.ToPointer(uint64(pi)).MemRead(infoSize)
+	if err != nil {
+		return
+	}
+	v, _, _ := bytes.Cut(info[4:], []byte{0})
+	if name != 0 {
+		n, _ := ctx.ToPointer(uint64(pi) + infoSize).MemReadString()
+		ctx.ToPointer(uint64(name)).MemWrite(append([]byte(n), 0))
+	}
+	if value != 0 {
+		ctx.ToPointer(uint64(value)).MemWrite(append(v, 0))
+	}
+	ctx.RetWrite(int32(len(v)))
+	ctx.Return()
+}
+
+func (f *areaFile) Close() error {
+	return nil
+}
+
+func (f *areaFile) Stat() (fs.FileInfo, error) {
+	return &f.info, nil
+}
+
+func (fi *fileInfo) Name() string {
+	return "__properties__"
+}
+
+func (fi *fileInfo) Size() int64 {
+	return areaSize
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	return 0o444
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return false
+}
+
+func (fi *fileInfo) Sys() any {
+	return nil
+}
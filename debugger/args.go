@@ -3,3 +3,9 @@ package debugger
 type Args interface {
 	Extract(...any) error
 }
+
+type Variadic []any
+
+type IndirectResult struct {
+	Val any
+}
@@ -0,0 +1,27 @@
+package debugger
+
+import "time"
+
+type ClockMode int
+
+const (
+	ClockMode_Host ClockMode = iota
+	ClockMode_Frozen
+	ClockMode_Instruction
+)
+
+type Clock interface {
+	Mode() ClockMode
+	SetMode(mode ClockMode) error
+	Now() time.Time
+	Set(t time.Time)
+	Monotonic() time.Duration
+	Advance(d time.Duration)
+	SetTick(d time.Duration)
+	Frequency() uint64
+	Counter() uint64
+}
+
+type ClockManager interface {
+	Clock() Clock
+}
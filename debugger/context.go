@@ -17,7 +17,10 @@ type Context interface {
 	ArgWrite(calling Calling, args ...any) error
 	RetExtract(val any) error
 	RetWrite(val any) error
+	RetExtractOf(calling Calling, val any) error
+	RetWriteOf(calling Calling, val any) error
 	Return() error
+	ReturnPop(size uint64) error
 	Goto(addr uint64) error
 	MemoryContext
 	StorageContext
@@ -14,6 +14,7 @@ type Debugger interface {
 	TaskManager
 	ModuleManager
 	FileManager
+	ClockManager
 }
 
 type DebuggerInfo interface {
@@ -29,6 +29,7 @@ type SimulateException interface {
 type simulateException struct {
 	ctx Context
 	mod string
+	sym string
 	pc  uint64
 }
 
@@ -55,9 +56,18 @@ type PanicException struct {
 	stack []byte
 }
 
+type InitException struct {
+	simulateException
+	name  string
+	index int
+	err   error
+}
+
 func (e *simulateException) String() string {
 	if e.mod == "" {
 		return fmt.Sprintf("pc: %016X", e.pc)
+	} else if e.sym != "" {
+		return fmt.Sprintf("%s!%s+0x%X", e.mod, e.sym, e.pc)
 	}
 	return fmt.Sprintf("module: %s, offset: %08X", e.mod, e.pc)
 }
@@ -106,16 +116,39 @@ func (e *PanicException) Panic() any {
 	return e.v
 }
 
+func (e *InitException) Error() string {
+	return fmt.Sprintf("[Init] %s, %s[%d]: %v", &e.simulateException, e.name, e.index, e.err)
+}
+
+func (e *InitException) Name() string {
+	return e.name
+}
+
+func (e *InitException) Index() int {
+	return e.index
+}
+
+func (e *InitException) Unwrap() error {
+	return e.err
+}
+
 func initException(ctx Context) simulateException {
 	pc, _ := ctx.RegRead(ctx.PC())
-	var mod string
-	if m, err := ctx.Debugger().FindModuleByAddr(pc); err == nil {
+	if ctx.Debugger().Arch() == emulator.ARCH_ARM {
+		pc &^= 1
+	}
+	var mod, sym string
+	if m, s, off, err := ctx.Debugger().FindSymbolByAddr(pc); m != nil {
 		mod = m.Name()
-		pc -= m.BaseAddr()
+		pc = off
+		if err == nil {
+			sym = s.Name
+		}
 	}
 	return simulateException{
 		ctx: ctx,
 		mod: mod,
+		sym: sym,
 		pc:  pc,
 	}
 }
@@ -150,3 +183,12 @@ func NewPanicException(ctx Context, v any, stack []byte) SimulateException {
 		stack:             stack,
 	}
 }
+
+func NewInitException(ctx Context, name string, index int, err error) SimulateException {
+	return &InitException{
+		simulateException: initException(ctx),
+		name:              name,
+		index:             index,
+		err:               err,
+	}
+}
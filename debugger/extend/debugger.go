@@ -8,6 +8,8 @@ import (
 	arm "github.com/wnxd/microdbg/internal/debugger/arm"
 	arm64 "github.com/wnxd/microdbg/internal/debugger/arm64"
 	"github.com/wnxd/microdbg/internal/debugger/extend"
+	x86 "github.com/wnxd/microdbg/internal/debugger/x86"
+	x86_64 "github.com/wnxd/microdbg/internal/debugger/x86_64"
 )
 
 type ExtendDebugger extend.ExtendDebugger
@@ -49,7 +51,9 @@ func newDbg[D ExtendDebugger](arch emulator.Arch) (ExtendDebugger, error) {
 	case emulator.ARCH_ARM64:
 		return new(arm64.Arm64Dbg), nil
 	case emulator.ARCH_X86:
+		return new(x86.X86Dbg), nil
 	case emulator.ARCH_X86_64:
+		return new(x86_64.X86_64Dbg), nil
 	}
 	return nil, emulator.ErrArchUnsupported
 }
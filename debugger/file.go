@@ -7,6 +7,21 @@ import (
 	"github.com/wnxd/microdbg/socket"
 )
 
+type FileOp int
+
+const (
+	FileOp_Open FileOp = iota
+	FileOp_Stat
+	FileOp_ReadDir
+	FileOp_Mkdir
+	FileOp_Readlink
+	FileOp_Socket
+	FileOp_Bind
+	FileOp_Connect
+)
+
+type FileFilter = func(op FileOp, name string) (FileHandler, error)
+
 type FileHandler interface {
 	OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error)
 	Stat(name string) (fs.FileInfo, error)
@@ -19,6 +34,7 @@ type FileHandler interface {
 type FileManager interface {
 	AddFileHandler(handler FileHandler)
 	RemoveFileHandler(handler FileHandler)
+	SetFileFilter(filter FileFilter)
 	CreateFileDescriptor(file filesystem.File) int
 	CloseFileDescriptor(fd int) (filesystem.File, error)
 	GetFile(fd int) (filesystem.File, error)
@@ -18,10 +18,18 @@ type InvalidCallback = func(ctx Context, data any) HookResult
 type MemoryCallback = func(ctx Context, typ emulator.HookType, addr, size, value uint64, data any) HookResult
 type CodeCallback = func(ctx Context, addr, size uint64, data any)
 type ControlCallback = func(ctx Context, data any)
+type SysRegCallback = func(ctx Context, sysreg uint64, data any) (uint64, bool)
+
+type InterruptTracer interface {
+	Enter(ctx Context, intno uint64)
+	Exit(ctx Context, intno uint64, result HookResult)
+}
 
 type HookManger interface {
 	AddHook(typ emulator.HookType, callback any, data any, begin, end uint64) (HookHandler, error)
 	AddControl(callback ControlCallback, data any) (ControlHandler, error)
+	AddSymbolHook(name string, typ emulator.HookType, callback any, data any) (HookHandler, error)
+	AddInterruptTracer(tracer InterruptTracer) io.Closer
 }
 
 type HookHandler interface {
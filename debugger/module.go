@@ -14,6 +14,8 @@ type SymbolIter interface {
 	Symbols(yield func(Symbol) bool)
 }
 
+type ModuleCallback = func(module Module)
+
 type Module interface {
 	io.Closer
 	Name() string
@@ -30,7 +32,12 @@ type ModuleManager interface {
 	FindModule(name string) (Module, error)
 	FindModuleByAddr(addr uint64) (Module, error)
 	FindSymbol(name string) (Module, uint64, error)
+	FindSymbolByAddr(addr uint64) (Module, Symbol, uint64, error)
+	Modules(yield func(Module) bool)
+	Symbols(yield func(Module, Symbol) bool)
 	GetModule(addr uint64) Module
+	OnModuleLoad(callback ModuleCallback) io.Closer
+	OnModuleUnload(callback ModuleCallback) io.Closer
 }
 
 var InternalModule Module = new(module)
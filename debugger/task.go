@@ -12,6 +12,9 @@ const (
 	Calling_Cdecl
 	Calling_Stdcall
 	Calling_Fastcall
+	Calling_SysV
+	Calling_Win64
+	Calling_Darwin
 	Calling_ATPCS = Calling_Fastcall
 )
 
@@ -42,6 +45,7 @@ type TaskManager interface {
 	GetMainTask(ctx context.Context) (Task, error)
 	CreateTask(ctx context.Context) (Task, error)
 	CallTaskOf(task Task, addr uint64) error
+	Tasks() []Task
 }
 
 func (s TaskStatus) Error() string {
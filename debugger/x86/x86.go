@@ -0,0 +1,9 @@
+package x86
+
+import (
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	internal "github.com/wnxd/microdbg/internal/debugger/x86"
+)
+
+var _ = debugger.Register(emulator.ARCH_X86, internal.NewX86Debugger)
@@ -0,0 +1,9 @@
+package x86_64
+
+import (
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	internal "github.com/wnxd/microdbg/internal/debugger/x86_64"
+)
+
+var _ = debugger.Register(emulator.ARCH_X86_64, internal.NewX86_64Debugger)
@@ -0,0 +1,7 @@
+package arm64
+
+const (
+	ARM64_SYSREG_CNTFRQ_EL0 = 0xdf00
+	ARM64_SYSREG_CNTPCT_EL0 = 0xdf01
+	ARM64_SYSREG_CNTVCT_EL0 = 0xdf02
+)
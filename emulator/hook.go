@@ -22,6 +22,7 @@ const (
 	HOOK_TYPE_MEM_WRITE
 	HOOK_TYPE_MEM_FETCH
 	HOOK_TYPE_MEM_READ_AFTER
+	HOOK_TYPE_INSN_SYS
 
 	HOOK_TYPE_MEM_UNMAPPED      = HOOK_TYPE_MEM_READ_UNMAPPED | HOOK_TYPE_MEM_WRITE_UNMAPPED | HOOK_TYPE_MEM_FETCH_UNMAPPED
 	HOOK_TYPE_MEM_PROT          = HOOK_TYPE_MEM_READ_PROT | HOOK_TYPE_MEM_WRITE_PROT | HOOK_TYPE_MEM_FETCH_PROT
@@ -36,6 +37,7 @@ type InterruptCallback = func(intno uint64, data any)
 type InvalidCallback = func(data any) bool
 type CodeCallback = func(addr, size uint64, data any)
 type MemoryCallback = func(typ HookType, addr, size, value uint64, data any) bool
+type SysRegCallback = func(sysreg uint64, data any) (uint64, bool)
 
 type Hook interface {
 	io.Closer
@@ -57,6 +59,7 @@ var hookTypeNames = map[HookType]string{
 	HOOK_TYPE_MEM_WRITE:          "MEM_WRITE",
 	HOOK_TYPE_MEM_FETCH:          "MEM_FETCH",
 	HOOK_TYPE_MEM_READ_AFTER:     "MEM_READ_AFTER",
+	HOOK_TYPE_INSN_SYS:           "INSN_SYS",
 }
 
 func (ht HookType) String() string {
@@ -17,6 +17,11 @@ type WriteFile interface {
 	Write(b []byte) (n int, err error)
 }
 
+type SeekFile interface {
+	File
+	Seek(offset int64, whence int) (int64, error)
+}
+
 type ControlFile interface {
 	File
 	Control(op int, arg any) error
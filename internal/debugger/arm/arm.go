@@ -11,6 +11,7 @@ import (
 const (
 	ARM_STACK_SIZE = 10 * 0x1000
 	POINTER_SIZE   = 4
+	CPSR_T         = 1 << 5
 )
 
 type ArmDbg struct {
@@ -115,7 +116,7 @@ func (dbg *ArmDbg) ArgWrite(ctx debugger.RegisterContext, calling debugger.Calli
 	return ptr.MemWrite(buf)
 }
 
-func (dbg *ArmDbg) RetExtract(ctx debugger.RegisterContext, val any) error {
+func (dbg *ArmDbg) RetExtract(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
 	if internal.GetPtr(val) == nil {
 		return debugger.ErrArgumentInvalid
 	}
@@ -123,7 +124,7 @@ func (dbg *ArmDbg) RetExtract(ctx debugger.RegisterContext, val any) error {
 	return encoding.Decode(stream, val)
 }
 
-func (dbg *ArmDbg) RetWrite(ctx debugger.RegisterContext, val any) error {
+func (dbg *ArmDbg) RetWrite(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
 	if internal.GetPtr(val) == nil {
 		return ctx.RegWrite(emu_arm.ARM_REG_R0, 0)
 	}
@@ -136,7 +137,24 @@ func (dbg *ArmDbg) Return(ctx debugger.RegisterContext) error {
 	if err != nil {
 		return err
 	}
-	return ctx.RegWrite(emu_arm.ARM_REG_PC, lr)
+	return dbg.Goto(ctx, lr)
+}
+
+func (dbg *ArmDbg) Goto(ctx debugger.RegisterContext, addr uint64) error {
+	cpsr, err := ctx.RegRead(emu_arm.ARM_REG_CPSR)
+	if err != nil {
+		return err
+	}
+	if addr&1 != 0 {
+		cpsr |= CPSR_T
+	} else {
+		cpsr &^= CPSR_T
+	}
+	err = ctx.RegWrite(emu_arm.ARM_REG_CPSR, cpsr)
+	if err != nil {
+		return err
+	}
+	return ctx.RegWrite(emu_arm.ARM_REG_PC, addr)
 }
 
 func (dbg *ArmDbg) InitStack() (uint64, error) {
@@ -165,7 +183,11 @@ func (dbg *ArmDbg) TaskControl(task debugger.Task, addr uint64) (debugger.Contro
 		return nil, err
 	}
 	ctx := task.Context()
-	ctx.RegWrite(emu_arm.ARM_REG_PC, addr)
+	err = dbg.Goto(ctx, addr)
+	if err != nil {
+		ctrl.Close()
+		return nil, err
+	}
 	ctx.RegWrite(emu_arm.ARM_REG_LR, ctrl.Addr())
 	return ctrl, nil
 }
@@ -0,0 +1,305 @@
+package arm64
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/wnxd/microdbg/debugger"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	"github.com/wnxd/microdbg/encoding"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+)
+
+const (
+	HFA_MAX_COUNT  = 4
+	INDIRECT_LIMIT = 2 * POINTER_SIZE
+)
+
+type indirectKey struct{}
+
+type aggregate struct {
+	size  int
+	align int
+	count int
+	kind  reflect.Kind
+}
+
+func classify(typ reflect.Type) (aggregate, bool) {
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Array:
+	default:
+		return aggregate{}, false
+	}
+	agg := aggregate{size: encoding.DecodeSize(POINTER_SIZE, reflect.New(typ).Interface()), align: typ.Align()}
+	if kind, count := homogeneous(typ); count > 0 && count <= HFA_MAX_COUNT {
+		agg.kind, agg.count = kind, count
+	}
+	return agg, true
+}
+
+func homogeneous(typ reflect.Type) (reflect.Kind, int) {
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return typ.Kind(), 1
+	case reflect.Array:
+		kind, count := homogeneous(typ.Elem())
+		return kind, count * typ.Len()
+	case reflect.Struct:
+		var (
+			base  reflect.Kind
+			total int
+		)
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			if field.Tag.Get("encoding") == "ignore" {
+				continue
+			}
+			kind, count := homogeneous(field.Type)
+			if count == 0 || (base != reflect.Invalid && base != kind) {
+				return reflect.Invalid, 0
+			}
+			base = kind
+			total += count
+		}
+		return base, total
+	}
+	return reflect.Invalid, 0
+}
+
+func (rs *regStream) encode(val any) error {
+	if args, ok := val.(debugger.Variadic); ok {
+		return rs.encodeVariadic(args)
+	} else if internal.GetPtr(val) == nil {
+		return encoding.Encode(rs, val)
+	} else if result, ok := val.(debugger.IndirectResult); ok {
+		return rs.indirect(result.Val)
+	}
+	agg, ok := classify(reflect.TypeOf(val))
+	if !ok {
+		return encoding.Encode(rs, rs.extend(val))
+	}
+	buf := make(internal.Buffer, agg.size)
+	err := encoding.Encode(rs.buffer(&buf), val)
+	if err != nil {
+		return err
+	}
+	return rs.writeAggregate(agg, buf)
+}
+
+func (rs *regStream) decode(val any) error {
+	if args, ok := val.(debugger.Variadic); ok {
+		return rs.decodeVariadic(args)
+	}
+	typ := reflect.TypeOf(val)
+	if internal.GetPtr(val) == nil || typ.Kind() != reflect.Pointer {
+		return encoding.Decode(rs, val)
+	}
+	agg, ok := classify(typ.Elem())
+	if !ok {
+		return encoding.Decode(rs, val)
+	} else if agg.count == 0 && agg.size > INDIRECT_LIMIT {
+		addr, err := rs.indirectAddr()
+		if err != nil {
+			return err
+		}
+		return encoding.Decode(internal.PointerStream(rs.dbg.ToPointer(addr), rs.ctx.StackAlloc, POINTER_SIZE), val)
+	}
+	buf, err := rs.readAggregate(agg)
+	if err != nil {
+		return err
+	}
+	return encoding.Decode(rs.buffer(&buf), val)
+}
+
+func (rs *regStream) encodeVariadic(args debugger.Variadic) error {
+	for _, arg := range args {
+		rs.variadic()
+		if f, ok := arg.(float32); ok {
+			arg = float64(f)
+		}
+		err := rs.encode(arg)
+		if err != nil {
+			return err
+		}
+		rs.Align()
+	}
+	rs.variadic()
+	return nil
+}
+
+func (rs *regStream) decodeVariadic(args debugger.Variadic) error {
+	for _, arg := range args {
+		rs.variadic()
+		var err error
+		if f, ok := arg.(*float32); ok {
+			var d float64
+			err = rs.decode(&d)
+			*f = float32(d)
+		} else {
+			err = rs.decode(arg)
+		}
+		if err != nil {
+			return err
+		}
+		rs.Align()
+	}
+	rs.variadic()
+	return nil
+}
+
+func (rs *regStream) variadic() {
+	if rs.darwin {
+		rs.groff, rs.vroff = ARG_REG_COUNT*POINTER_SIZE, ARG_REG_COUNT
+		rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+	}
+}
+
+func (rs *regStream) extend(val any) any {
+	if !rs.darwin || rs.groff >= ARG_REG_COUNT*POINTER_SIZE {
+		return val
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return uint32(1)
+		}
+		return uint32(0)
+	case reflect.Int8, reflect.Int16:
+		return int32(rv.Int())
+	case reflect.Uint8, reflect.Uint16:
+		return uint32(rv.Uint())
+	}
+	return val
+}
+
+func (rs *regStream) indirect(val any) error {
+	size := encoding.DecodeSize(POINTER_SIZE, val)
+	ptr, err := rs.ctx.StackAlloc(uint64(size))
+	if err != nil {
+		return err
+	}
+	addr := ptr.Address()
+	if storage, ok := rs.ctx.(debugger.StorageContext); ok {
+		storage.LocalStore(indirectKey{}, addr)
+	}
+	return rs.ctx.RegWrite(emu_arm64.ARM64_REG_X8, addr)
+}
+
+func (rs *regStream) indirectAddr() (uint64, error) {
+	if !rs.ret {
+		var addr uint64
+		_, err := rs.Read(internal.ToPtrRaw(&addr))
+		return addr, err
+	} else if storage, ok := rs.ctx.(debugger.StorageContext); ok {
+		if addr, ok := storage.LocalLoad(indirectKey{}); ok {
+			storage.LocalDelete(indirectKey{})
+			return addr.(uint64), nil
+		}
+	}
+	return rs.ctx.RegRead(emu_arm64.ARM64_REG_X8)
+}
+
+func (rs *regStream) writeAggregate(agg aggregate, buf internal.Buffer) error {
+	switch {
+	case agg.count > 0:
+		if rs.vroff+agg.count > ARG_REG_COUNT {
+			rs.vroff = ARG_REG_COUNT
+			return rs.writeStack(buf, agg.align)
+		}
+		for i := range agg.count {
+			var err error
+			if agg.kind == reflect.Float32 {
+				err = rs.WriteFloat(internal.ReadPtrRaw[float32](buf[i*4:]))
+			} else {
+				err = rs.WriteDouble(internal.ReadPtrRaw[float64](buf[i*8:]))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case agg.size > INDIRECT_LIMIT:
+		if rs.ret {
+			addr, err := rs.ctx.RegRead(emu_arm64.ARM64_REG_X8)
+			if err != nil {
+				return err
+			}
+			return rs.dbg.ToPointer(addr).MemWrite(buf)
+		}
+		ptr, err := rs.ctx.StackAlloc(uint64(agg.size))
+		if err != nil {
+			return err
+		}
+		err = ptr.MemWrite(buf)
+		if err != nil {
+			return err
+		}
+		addr := ptr.Address()
+		_, err = rs.Write(internal.ToPtrRaw(&addr))
+		return err
+	}
+	buf = append(buf, make(internal.Buffer, debugger.Align(len(buf), POINTER_SIZE)-len(buf))...)
+	if rs.groff+len(buf) > ARG_REG_COUNT*POINTER_SIZE {
+		rs.groff = ARG_REG_COUNT * POINTER_SIZE
+		return rs.writeStack(buf[:agg.size], agg.align)
+	}
+	_, err := rs.Write(buf)
+	return err
+}
+
+func (rs *regStream) readAggregate(agg aggregate) (internal.Buffer, error) {
+	buf := make(internal.Buffer, debugger.Align(agg.size, POINTER_SIZE))
+	if agg.count > 0 {
+		if rs.vroff+agg.count > ARG_REG_COUNT {
+			rs.vroff = ARG_REG_COUNT
+			return buf, rs.readStack(buf[:agg.size], agg.align)
+		}
+		for i := range agg.count {
+			if agg.kind == reflect.Float32 {
+				f, err := rs.ReadFloat()
+				if err != nil {
+					return nil, err
+				}
+				copy(buf[i*4:], internal.ToPtrRaw(&f))
+			} else {
+				d, err := rs.ReadDouble()
+				if err != nil {
+					return nil, err
+				}
+				copy(buf[i*8:], internal.ToPtrRaw(&d))
+			}
+		}
+		return buf, nil
+	}
+	if rs.groff+len(buf) > ARG_REG_COUNT*POINTER_SIZE {
+		rs.groff = ARG_REG_COUNT * POINTER_SIZE
+		return buf, rs.readStack(buf[:agg.size], agg.align)
+	}
+	_, err := rs.Read(buf)
+	return buf, err
+}
+
+func (rs *regStream) readStack(buf internal.Buffer, align int) error {
+	if rs.stack == nil {
+		return errors.ErrUnsupported
+	}
+	rs.stackAlign(align)
+	n, err := rs.stack.ReadAt(buf, int64(rs.stoff))
+	rs.stoff += n
+	return err
+}
+
+func (rs *regStream) writeStack(buf internal.Buffer, align int) error {
+	if rs.stack == nil {
+		return errors.ErrUnsupported
+	}
+	rs.stackAlign(align)
+	n, err := rs.stack.WriteAt(buf, int64(rs.stoff))
+	rs.stoff += n
+	return err
+}
+
+func (rs *regStream) buffer(buf *internal.Buffer) encoding.Stream {
+	return internal.BufferStream(buf, rs.dbg.ToPointer, rs.ctx.StackAlloc, POINTER_SIZE)
+}
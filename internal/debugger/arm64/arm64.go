@@ -4,7 +4,6 @@ import (
 	"github.com/wnxd/microdbg/debugger"
 	"github.com/wnxd/microdbg/emulator"
 	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
-	"github.com/wnxd/microdbg/encoding"
 	internal "github.com/wnxd/microdbg/internal/debugger"
 	inter_arm "github.com/wnxd/microdbg/internal/debugger/arm"
 )
@@ -16,6 +15,7 @@ const (
 
 type Arm64Dbg struct {
 	internal.Dbg
+	sysreg emulator.Hook
 }
 
 func NewArm64Debugger(emu emulator.Emulator) (debugger.Debugger, error) {
@@ -33,10 +33,17 @@ func (dbg *Arm64Dbg) Init(impl internal.Debugger, emu emulator.Emulator) error {
 		return err
 	}
 	dbg.enableVFP()
+	if hook, err := emu.Hook(emulator.HOOK_TYPE_INSN_SYS, dbg.handleSysReg, nil, 1, 0); err == nil {
+		dbg.sysreg = hook
+	}
 	return nil
 }
 
 func (dbg *Arm64Dbg) Close() error {
+	if dbg.sysreg != nil {
+		dbg.sysreg.Close()
+		dbg.sysreg = nil
+	}
 	return dbg.Dbg.Close()
 }
 
@@ -68,6 +75,7 @@ func (dbg *Arm64Dbg) Args(ctx debugger.RegisterContext, calling debugger.Calling
 	switch calling {
 	case debugger.Calling_Default:
 	case debugger.Calling_Fastcall:
+	case debugger.Calling_Darwin:
 	default:
 		return nil, debugger.ErrCallingUnsupported
 	}
@@ -76,10 +84,10 @@ func (dbg *Arm64Dbg) Args(ctx debugger.RegisterContext, calling debugger.Calling
 		return nil, err
 	}
 	var index int
-	stream := &regStream{dbg: dbg, ctx: ctx, stack: dbg.ToPointer(stackAddr)}
+	stream := &regStream{dbg: dbg, ctx: ctx, darwin: calling == debugger.Calling_Darwin, stack: dbg.ToPointer(stackAddr)}
 	return internal.Args(func(args ...any) error {
 		for _, arg := range args {
-			err := encoding.Decode(stream, arg)
+			err := stream.decode(arg)
 			if err != nil {
 				return err
 			}
@@ -94,13 +102,14 @@ func (dbg *Arm64Dbg) ArgWrite(ctx debugger.RegisterContext, calling debugger.Cal
 	switch calling {
 	case debugger.Calling_Default:
 	case debugger.Calling_Fastcall:
+	case debugger.Calling_Darwin:
 	default:
 		return debugger.ErrCallingUnsupported
 	}
 	var buf internal.Buffer
-	stream := &regStream{dbg: dbg, ctx: ctx, stack: &buf}
+	stream := &regStream{dbg: dbg, ctx: ctx, darwin: calling == debugger.Calling_Darwin, stack: &buf}
 	for _, arg := range args {
-		err := encoding.Encode(stream, arg)
+		err := stream.encode(arg)
 		if err != nil {
 			return err
 		}
@@ -116,20 +125,20 @@ func (dbg *Arm64Dbg) ArgWrite(ctx debugger.RegisterContext, calling debugger.Cal
 	return ptr.MemWrite(buf)
 }
 
-func (dbg *Arm64Dbg) RetExtract(ctx debugger.RegisterContext, val any) error {
+func (dbg *Arm64Dbg) RetExtract(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
 	if internal.GetPtr(val) == nil {
 		return debugger.ErrArgumentInvalid
 	}
-	stream := &regStream{dbg: dbg, ctx: ctx}
-	return encoding.Decode(stream, val)
+	stream := &regStream{dbg: dbg, ctx: ctx, ret: true}
+	return stream.decode(val)
 }
 
-func (dbg *Arm64Dbg) RetWrite(ctx debugger.RegisterContext, val any) error {
+func (dbg *Arm64Dbg) RetWrite(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
 	if internal.GetPtr(val) == nil {
 		return ctx.RegWrite(emu_arm64.ARM64_REG_X0, 0)
 	}
-	stream := &regStream{dbg: dbg, ctx: ctx}
-	return encoding.Encode(stream, val)
+	stream := &regStream{dbg: dbg, ctx: ctx, ret: true}
+	return stream.encode(val)
 }
 
 func (dbg *Arm64Dbg) Return(ctx debugger.RegisterContext) error {
@@ -171,6 +180,16 @@ func (dbg *Arm64Dbg) TaskControl(task debugger.Task, addr uint64) (debugger.Cont
 	return ctrl, nil
 }
 
+func (dbg *Arm64Dbg) handleSysReg(sysreg uint64, data any) (uint64, bool) {
+	switch sysreg {
+	case emu_arm64.ARM64_SYSREG_CNTFRQ_EL0:
+		return dbg.Clock().Frequency(), true
+	case emu_arm64.ARM64_SYSREG_CNTPCT_EL0, emu_arm64.ARM64_SYSREG_CNTVCT_EL0:
+		return dbg.Clock().Counter(), true
+	}
+	return 0, false
+}
+
 func (dbg *Arm64Dbg) enableVFP() {
 	emu := dbg.Emulator()
 	val, _ := emu.RegRead(emu_arm64.ARM64_REG_CPACR_EL1)
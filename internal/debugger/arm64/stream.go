@@ -15,20 +15,24 @@ import (
 const ARG_REG_COUNT = 8
 
 type regStream struct {
-	dbg   debugger.Debugger
-	ctx   debugger.RegisterContext
-	stoff int
-	groff int
-	vroff int
-	value uint64
-	stack interface {
+	dbg    debugger.Debugger
+	ctx    debugger.RegisterContext
+	stoff  int
+	groff  int
+	vroff  int
+	ret    bool
+	darwin bool
+	value  uint64
+	stack  interface {
 		io.ReaderAt
 		io.WriterAt
 	}
 }
 
 func (rs *regStream) Align() {
-	rs.stoff = debugger.Align(rs.stoff, 4)
+	if !rs.darwin {
+		rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+	}
 	rs.groff = debugger.Align(rs.groff, POINTER_SIZE)
 }
 
@@ -51,6 +55,7 @@ func (rs *regStream) Skip(n int) error {
 
 func (rs *regStream) Read(b []byte) (int, error) {
 	if rs.groff >= ARG_REG_COUNT*POINTER_SIZE {
+		rs.stackAlign(len(b))
 		n, err := rs.stack.ReadAt(b, int64(rs.stoff))
 		rs.stoff += n
 		return n, err
@@ -84,6 +89,7 @@ func (rs *regStream) Read(b []byte) (int, error) {
 func (rs *regStream) ReadFloat() (float32, error) {
 	if rs.vroff >= ARG_REG_COUNT {
 		var f float32
+		rs.stackAlign(4)
 		_, err := rs.stack.ReadAt(internal.ToPtrRaw(&f), int64(rs.stoff))
 		rs.stoff += 4
 		return f, err
@@ -99,6 +105,7 @@ func (rs *regStream) ReadFloat() (float32, error) {
 func (rs *regStream) ReadDouble() (float64, error) {
 	if rs.vroff >= ARG_REG_COUNT {
 		var d float64
+		rs.stackAlign(8)
 		_, err := rs.stack.ReadAt(internal.ToPtrRaw(&d), int64(rs.stoff))
 		rs.stoff += 8
 		return d, err
@@ -126,6 +133,7 @@ func (rs *regStream) ReadStream() (encoding.Stream, error) {
 
 func (rs *regStream) Write(b []byte) (int, error) {
 	if rs.groff >= ARG_REG_COUNT*POINTER_SIZE {
+		rs.stackAlign(len(b))
 		n, err := rs.stack.WriteAt(b, int64(rs.stoff))
 		rs.stoff += n
 		return n, err
@@ -161,6 +169,7 @@ func (rs *regStream) Write(b []byte) (int, error) {
 
 func (rs *regStream) WriteFloat(f float32) error {
 	if rs.vroff >= ARG_REG_COUNT {
+		rs.stackAlign(4)
 		_, err := rs.stack.WriteAt(internal.ToPtrRaw(&f), int64(rs.stoff))
 		rs.stoff += 4
 		return err
@@ -175,11 +184,12 @@ func (rs *regStream) WriteFloat(f float32) error {
 
 func (rs *regStream) WriteDouble(d float64) error {
 	if rs.vroff >= ARG_REG_COUNT {
+		rs.stackAlign(8)
 		_, err := rs.stack.WriteAt(internal.ToPtrRaw(&d), int64(rs.stoff))
 		rs.stoff += 8
 		return err
 	}
-	err := rs.ctx.RegWrite(emu_arm64.ARM64_REG_S0+emulator.Reg(rs.vroff), math.Float64bits(d))
+	err := rs.ctx.RegWrite(emu_arm64.ARM64_REG_D0+emulator.Reg(rs.vroff), math.Float64bits(d))
 	if err != nil {
 		return err
 	}
@@ -203,3 +213,9 @@ func (rs *regStream) WriteStream(size int) (encoding.Stream, error) {
 	}
 	return internal.PointerStream(ptr, rs.ctx.StackAlloc, POINTER_SIZE), nil
 }
+
+func (rs *regStream) stackAlign(align int) {
+	if rs.darwin && align&(align-1) == 0 {
+		rs.stoff = debugger.Align(rs.stoff, min(align, POINTER_SIZE))
+	}
+}
@@ -0,0 +1,162 @@
+package debugger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	clockFrequency = 24000000
+	clockTick      = time.Nanosecond
+)
+
+var clockEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type clockManager struct {
+	clock virtualClock
+}
+
+type virtualClock struct {
+	mu      sync.Mutex
+	emu     emulator.Emulator
+	mode    debugger.ClockMode
+	epoch   time.Time
+	fixed   bool
+	host    time.Time
+	elapsed time.Duration
+	tick    time.Duration
+	insns   uint64
+	hook    emulator.Hook
+}
+
+func (cm *clockManager) ctor(emu emulator.Emulator) {
+	cm.clock.ctor(emu)
+}
+
+func (cm *clockManager) dtor() {
+	cm.clock.dtor()
+}
+
+func (c *virtualClock) ctor(emu emulator.Emulator) {
+	c.emu = emu
+	c.host = time.Now()
+	c.epoch = c.host
+	c.tick = clockTick
+}
+
+func (c *virtualClock) dtor() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hook != nil {
+		c.hook.Close()
+		c.hook = nil
+	}
+}
+
+func (c *virtualClock) Mode() debugger.ClockMode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mode
+}
+
+func (c *virtualClock) SetMode(mode debugger.ClockMode) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mode == c.mode {
+		return nil
+	}
+	var hook emulator.Hook
+	switch mode {
+	case debugger.ClockMode_Host, debugger.ClockMode_Frozen:
+	case debugger.ClockMode_Instruction:
+		var err error
+		hook, err = c.emu.Hook(emulator.HOOK_TYPE_CODE, c.handleCode, nil, 1, 0)
+		if err != nil {
+			return err
+		}
+	default:
+		return debugger.ErrArgumentInvalid
+	}
+	c.elapsed = c.monotonic()
+	c.host = time.Now()
+	atomic.StoreUint64(&c.insns, 0)
+	if !c.fixed {
+		if mode == debugger.ClockMode_Host {
+			c.epoch = c.host.Add(-c.elapsed)
+		} else {
+			c.epoch = clockEpoch.Add(-c.elapsed)
+		}
+	}
+	if c.hook != nil {
+		c.hook.Close()
+	}
+	c.hook = hook
+	c.mode = mode
+	return nil
+}
+
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch.Add(c.monotonic())
+}
+
+func (c *virtualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.epoch = t.Add(-c.monotonic())
+	c.fixed = true
+}
+
+func (c *virtualClock) Monotonic() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.monotonic()
+}
+
+func (c *virtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.elapsed += d
+	}
+}
+
+func (c *virtualClock) SetTick(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.elapsed = c.monotonic()
+	atomic.StoreUint64(&c.insns, 0)
+	c.tick = d
+}
+
+func (c *virtualClock) Frequency() uint64 {
+	return clockFrequency
+}
+
+func (c *virtualClock) Counter() uint64 {
+	ns := uint64(c.Monotonic())
+	return ns/uint64(time.Second)*clockFrequency + ns%uint64(time.Second)*clockFrequency/uint64(time.Second)
+}
+
+func (c *virtualClock) monotonic() time.Duration {
+	switch c.mode {
+	case debugger.ClockMode_Host:
+		return c.elapsed + time.Since(c.host)
+	case debugger.ClockMode_Instruction:
+		return c.elapsed + time.Duration(atomic.LoadUint64(&c.insns))*c.tick
+	}
+	return c.elapsed
+}
+
+func (c *virtualClock) handleCode(addr, size uint64, data any) {
+	atomic.AddUint64(&c.insns, 1)
+}
+
+func (dbg *Dbg) Clock() debugger.Clock {
+	return &dbg.clockManager.clock
+}
@@ -116,20 +116,40 @@ func (bc *baseContext[Impl]) ArgWrite(calling debugger.Calling, args ...any) err
 }
 
 func (bc *baseContext[Impl]) RetExtract(val any) error {
-	return bc.dbg.RetExtract(bc.impl(), val)
+	return bc.dbg.RetExtract(bc.impl(), debugger.Calling_Default, val)
 }
 
 func (bc *baseContext[Impl]) RetWrite(val any) error {
-	return bc.dbg.RetWrite(bc.impl(), val)
+	return bc.dbg.RetWrite(bc.impl(), debugger.Calling_Default, val)
+}
+
+func (bc *baseContext[Impl]) RetExtractOf(calling debugger.Calling, val any) error {
+	return bc.dbg.RetExtract(bc.impl(), calling, val)
+}
+
+func (bc *baseContext[Impl]) RetWriteOf(calling debugger.Calling, val any) error {
+	return bc.dbg.RetWrite(bc.impl(), calling, val)
 }
 
 func (bc *baseContext[Impl]) Return() error {
 	return bc.dbg.Return(bc.impl())
 }
 
-func (bc *baseContext[Impl]) Goto(addr uint64) error {
+func (bc *baseContext[Impl]) ReturnPop(size uint64) error {
 	ctx := bc.impl()
-	return ctx.RegWrite(ctx.PC(), addr)
+	err := bc.dbg.Return(ctx)
+	if err != nil || size == 0 {
+		return err
+	}
+	stackAddr, err := ctx.RegRead(bc.SP())
+	if err != nil {
+		return err
+	}
+	return ctx.RegWrite(bc.SP(), stackAddr+size)
+}
+
+func (bc *baseContext[Impl]) Goto(addr uint64) error {
+	return bc.dbg.Goto(bc.impl(), addr)
 }
 
 func (bc *baseContext[Impl]) ToPointer(addr uint64) emulator.Pointer {
@@ -11,13 +11,16 @@ type Debugger interface {
 	SP() emulator.Reg
 	Args(debugger.RegisterContext, debugger.Calling) (debugger.Args, error)
 	ArgWrite(debugger.RegisterContext, debugger.Calling, ...any) error
-	RetExtract(debugger.RegisterContext, any) error
-	RetWrite(debugger.RegisterContext, any) error
+	RetExtract(debugger.RegisterContext, debugger.Calling, any) error
+	RetWrite(debugger.RegisterContext, debugger.Calling, any) error
 	Return(debugger.RegisterContext) error
+	Goto(debugger.RegisterContext, uint64) error
 	InitStack() (uint64, error)
 	CloseStack(uint64) error
 	TaskControl(debugger.Task, uint64) (debugger.ControlHandler, error)
 	taskID() int
+	trackTask(debugger.Task)
+	untrackTask(debugger.Task)
 	newTaskContext(Debugger) (*taskContext, error)
 	allocTaskContext() (*taskContext, error)
 	freeTaskContext(*taskContext)
@@ -35,11 +38,13 @@ type Dbg struct {
 	fileManager
 	moduleManager
 	taskManager
+	clockManager
 }
 
 func (dbg *Dbg) Init(impl Debugger, emu emulator.Emulator) error {
 	dbg.impl = impl
 	dbg.emu = emu
+	dbg.clockManager.ctor(emu)
 	dbg.memoryManager.ctor()
 	dbg.hookManger.ctor(dbg.impl)
 	dbg.fileManager.ctor()
@@ -54,9 +59,14 @@ func (dbg *Dbg) Close() error {
 	dbg.fileManager.dtor()
 	dbg.hookManger.dtor()
 	dbg.memoryManager.dtor(dbg.impl)
+	dbg.clockManager.dtor()
 	return nil
 }
 
 func (dbg *Dbg) Emulator() emulator.Emulator {
 	return dbg.emu
 }
+
+func (dbg *Dbg) Goto(ctx debugger.RegisterContext, addr uint64) error {
+	return ctx.RegWrite(dbg.impl.PC(), addr)
+}
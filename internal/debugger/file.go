@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"sync"
@@ -19,8 +20,22 @@ type fileRef struct {
 	count int64
 }
 
+type dirRef struct {
+	filesystem.Dir
+	fm   *fileManager
+	name string
+}
+
+type filterSocket struct {
+	socket.Socket
+	network socket.Network
+	filter  debugger.FileFilter
+}
+
 type fileManager struct {
 	handlers []debugger.FileHandler
+	filterRW sync.RWMutex
+	filter   debugger.FileFilter
 	fd       int64
 	fileRW   sync.RWMutex
 	fileMap  map[int]filesystem.File
@@ -48,6 +63,18 @@ func (fm *fileManager) RemoveFileHandler(handler debugger.FileHandler) {
 	fm.handlers = slices.DeleteFunc(fm.handlers, func(h debugger.FileHandler) bool { return h == handler })
 }
 
+func (fm *fileManager) SetFileFilter(filter debugger.FileFilter) {
+	fm.filterRW.Lock()
+	fm.filter = filter
+	fm.filterRW.Unlock()
+}
+
+func (fm *fileManager) getFileFilter() debugger.FileFilter {
+	fm.filterRW.RLock()
+	defer fm.filterRW.RUnlock()
+	return fm.filter
+}
+
 func (fm *fileManager) CreateFileDescriptor(file filesystem.File) int {
 	fd := int(atomic.AddInt64(&fm.fd, 1))
 	fm.fileRW.Lock()
@@ -130,18 +157,29 @@ func (fm *fileManager) Open(name string) (fs.File, error) {
 
 func (fm *fileManager) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
 	name = filepath.ToSlash(filepath.Join("/", name))
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_Open, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
 		f, err := handler.OpenFile(name, flag, perm)
-		if err == nil {
-			return f, nil
+		if err != nil {
+			continue
+		} else if dir, ok := f.(filesystem.Dir); ok {
+			return &dirRef{Dir: dir, fm: fm, name: name}, nil
 		}
+		return f, nil
 	}
 	return nil, fs.ErrNotExist
 }
 
 func (fm *fileManager) Stat(name string) (fs.FileInfo, error) {
 	name = filepath.ToSlash(filepath.Join("/", name))
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_Stat, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
 		fi, err := handler.Stat(name)
 		if err == nil {
 			return fi, nil
@@ -152,7 +190,11 @@ func (fm *fileManager) Stat(name string) (fs.FileInfo, error) {
 
 func (fm *fileManager) ReadDir(name string) ([]fs.DirEntry, error) {
 	name = filepath.ToSlash(filepath.Join("/", name))
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_ReadDir, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
 		list, err := handler.ReadDir(name)
 		if err == nil {
 			return list, nil
@@ -163,7 +205,11 @@ func (fm *fileManager) ReadDir(name string) ([]fs.DirEntry, error) {
 
 func (fm *fileManager) Mkdir(name string, perm fs.FileMode) (filesystem.DirFS, error) {
 	name = filepath.ToSlash(filepath.Join("/", name))
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_Mkdir, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
 		dir, err := handler.Mkdir(name, perm)
 		if err == nil {
 			return dir, nil
@@ -174,7 +220,11 @@ func (fm *fileManager) Mkdir(name string, perm fs.FileMode) (filesystem.DirFS, e
 
 func (fm *fileManager) Readlink(name string) (string, error) {
 	name = filepath.ToSlash(filepath.Join("/", name))
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_Readlink, name)
+	if err != nil {
+		return "", err
+	}
+	for _, handler := range handlers {
 		path, err := handler.Readlink(name)
 		if err == nil {
 			return path, nil
@@ -184,13 +234,100 @@ func (fm *fileManager) Readlink(name string) (string, error) {
 }
 
 func (fm *fileManager) NewSocket(network socket.Network) (socket.Socket, error) {
-	for _, handler := range fm.handlers {
+	handlers, err := fm.route(debugger.FileOp_Socket, network)
+	if err != nil {
+		return nil, err
+	}
+	for _, handler := range handlers {
 		s, err := handler.NewSocket(network)
 		if err == nil {
-			return s, nil
+			return fm.wrapSocket(network, s), nil
 		}
 	}
-	return socket.New(network), nil
+	return fm.wrapSocket(network, socket.New(network)), nil
+}
+
+func (fm *fileManager) route(op debugger.FileOp, name string) ([]debugger.FileHandler, error) {
+	filter := fm.getFileFilter()
+	if filter == nil {
+		return fm.handlers, nil
+	}
+	handler, err := filter(op, name)
+	if err != nil {
+		return nil, err
+	} else if handler != nil {
+		return []debugger.FileHandler{handler}, nil
+	}
+	return fm.handlers, nil
+}
+
+func (fm *fileManager) wrapSocket(network socket.Network, s socket.Socket) socket.Socket {
+	filter := fm.getFileFilter()
+	if filter == nil {
+		return s
+	}
+	return &filterSocket{Socket: s, network: network, filter: filter}
+}
+
+func (d *dirRef) Read(b []byte) (int, error) {
+	if r, ok := d.Dir.(filesystem.ReadFile); ok {
+		return r.Read(b)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (d *dirRef) Write(b []byte) (int, error) {
+	if w, ok := d.Dir.(filesystem.WriteFile); ok {
+		return w.Write(b)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (d *dirRef) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := d.Dir.(filesystem.SeekFile); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, errors.ErrUnsupported
+}
+
+func (d *dirRef) Control(op int, arg any) error {
+	if ctl, ok := d.Dir.(filesystem.ControlFile); ok {
+		return ctl.Control(op, arg)
+	}
+	return errors.ErrUnsupported
+}
+
+func (d *dirRef) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	return d.fm.OpenFile(path.Join(d.name, name), flag, perm)
+}
+
+func (d *dirRef) Mkdir(name string, perm fs.FileMode) error {
+	_, err := d.fm.Mkdir(path.Join(d.name, name), perm)
+	return err
+}
+
+func (d *dirRef) Readlink(name string) (string, error) {
+	link, err := d.fm.Readlink(path.Join(d.name, name))
+	if err == nil {
+		return link, nil
+	} else if rl, ok := d.Dir.(filesystem.ReadlinkDir); ok {
+		return rl.Readlink(name)
+	}
+	return "", err
+}
+
+func (s *filterSocket) Bind(addr string) error {
+	if _, err := s.filter(debugger.FileOp_Bind, s.network+"://"+addr); err != nil {
+		return err
+	}
+	return s.Socket.Bind(addr)
+}
+
+func (s *filterSocket) Connect(addr string) error {
+	if _, err := s.filter(debugger.FileOp_Connect, s.network+"://"+addr); err != nil {
+		return err
+	}
+	return s.Socket.Connect(addr)
 }
 
 func (f *fileRef) Close() error {
@@ -221,6 +358,13 @@ func (f *fileRef) Write(b []byte) (int, error) {
 	return 0, errors.ErrUnsupported
 }
 
+func (f *fileRef) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.file.(filesystem.SeekFile); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, errors.ErrUnsupported
+}
+
 func (f *fileRef) ReadDir(n int) ([]fs.DirEntry, error) {
 	if dir, ok := f.file.(filesystem.DirFile); ok {
 		return dir.ReadDir(n)
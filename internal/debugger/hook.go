@@ -1,6 +1,7 @@
 package debugger
 
 import (
+	"io"
 	"runtime"
 	"sync"
 
@@ -13,6 +14,7 @@ type hookManger struct {
 	ctrlAddrs []chan [2]uint64
 	ctrlRange [][2]uint64
 	intrHooks sync.Map
+	tracers   sync.Map
 	insnHooks sync.Map
 	memHooks  sync.Map
 }
@@ -33,6 +35,29 @@ type memHandler struct {
 	hookHandler[debugger.MemoryCallback]
 }
 
+type sysRegHandler struct {
+	hookHandler[debugger.SysRegCallback]
+}
+
+type symbolHook struct {
+	mu       sync.Mutex
+	releases []func() error
+	dbg      Debugger
+	name     string
+	typ      emulator.HookType
+	callback any
+	data     any
+	module   debugger.Module
+	hook     debugger.HookHandler
+	err      error
+	closed   bool
+}
+
+type tracerHandler struct {
+	tracers *sync.Map
+	tracer  debugger.InterruptTracer
+}
+
 type controlHandler struct {
 	releases []func() error
 	addr     [2]uint64
@@ -71,13 +96,15 @@ func (h *hookManger) allocCtrlAddrs(dbg Debugger) error {
 	})
 	emu := dbg.Emulator()
 	var asm []byte
+	var thumb uint64
 	switch emu.Arch() {
 	case emulator.ARCH_ARM:
-		asm = []byte{0x35, 0x00, 0x00, 0xEF}
+		asm = []byte{0x35, 0xDF}
+		thumb = 1
 	case emulator.ARCH_ARM64:
 		asm = []byte{0xA1, 0x06, 0x00, 0xD4}
 	case emulator.ARCH_X86, emulator.ARCH_X86_64:
-		// asm = []byte{0x90}
+		asm = []byte{0xCD, 0x80}
 	}
 	size := uint64(len(asm))
 	count := region.Size / size
@@ -85,7 +112,7 @@ func (h *hookManger) allocCtrlAddrs(dbg Debugger) error {
 	ch := make(chan [2]uint64, count)
 	for addr := region.Addr; addr < end; addr += size {
 		emu.MemWrite(addr, asm)
-		ch <- [2]uint64{addr, addr + size}
+		ch <- [2]uint64{addr | thumb, addr + size}
 	}
 	h.ctrlAddrs = append(h.ctrlAddrs, ch)
 	h.ctrlRange = append(h.ctrlRange, [2]uint64{region.Addr, end + size})
@@ -156,6 +183,18 @@ func (h *hookManger) addHook(dbg Debugger, typ emulator.HookType, callback any,
 		}
 		handler.releases = append(handler.releases, hook.Close)
 		return handler, nil
+	case emulator.HOOK_TYPE_INSN_SYS:
+		callback, ok := callback.(debugger.SysRegCallback)
+		if !ok {
+			return nil, debugger.ErrHookCallbackType
+		}
+		handler := &sysRegHandler{hookHandler: hookHandler[debugger.SysRegCallback]{typ: typ, callback: callback, data: data}}
+		hook, err := dbg.Emulator().Hook(typ, handler.handleSysReg, dbg, begin, end)
+		if err != nil {
+			return nil, err
+		}
+		handler.releases = append(handler.releases, hook.Close)
+		return handler, nil
 	default:
 		callback, ok := callback.(debugger.MemoryCallback)
 		if !ok {
@@ -207,6 +246,28 @@ func (h *hookManger) addControl(dbg Debugger, callback debugger.ControlCallback,
 	return handler, nil
 }
 
+func (h *hookManger) addSymbolHook(dbg Debugger, name string, typ emulator.HookType, callback any, data any) (debugger.HookHandler, error) {
+	if !checkCallback(typ, callback) {
+		return nil, debugger.ErrHookCallbackType
+	}
+	handler := &symbolHook{dbg: dbg, name: name, typ: typ, callback: callback, data: data}
+	handler.releases = append(handler.releases, dbg.OnModuleLoad(handler.handleLoad).Close, dbg.OnModuleUnload(handler.handleUnload).Close)
+	if module, _, err := dbg.FindSymbol(name); err == nil {
+		err = handler.attach(module)
+		if err != nil {
+			handler.Close()
+			return nil, err
+		}
+	}
+	return handler, nil
+}
+
+func (h *hookManger) addInterruptTracer(tracer debugger.InterruptTracer) io.Closer {
+	handler := &tracerHandler{tracers: &h.tracers, tracer: tracer}
+	h.tracers.Store(handler, struct{}{})
+	return handler
+}
+
 func (h *hookManger) handleInterrupt(intno uint64, data any) {
 	data.(Debugger).asyncTask(func(task debugger.Task) {
 		result := debugger.HookResult_Next
@@ -217,6 +278,11 @@ func (h *hookManger) handleInterrupt(intno uint64, data any) {
 			return
 		}
 		isCtrl := h.isControl(pc)
+		if !isCtrl {
+			for handler := range h.tracers.Range {
+				handler.(*tracerHandler).tracer.Enter(ctx, intno)
+			}
+		}
 		for hook := range h.intrHooks.Range {
 			handler := hook.(*hookHandler[debugger.InterruptCallback])
 			if handler.valid(emulator.HOOK_TYPE_INTR, pc, isCtrl) {
@@ -226,6 +292,11 @@ func (h *hookManger) handleInterrupt(intno uint64, data any) {
 				}
 			}
 		}
+		if !isCtrl {
+			for handler := range h.tracers.Range {
+				handler.(*tracerHandler).tracer.Exit(ctx, intno, result)
+			}
+		}
 		if result == debugger.HookResult_Next {
 			task.CancelCause(debugger.NewInterruptException(ctx, intno))
 		}
@@ -336,6 +407,82 @@ func (h *memHandler) handleMemory(typ emulator.HookType, addr, size, value uint6
 	return true
 }
 
+func (h *sysRegHandler) handleSysReg(sysreg uint64, data any) (value uint64, ok bool) {
+	data.(Debugger).syncTask(func(task debugger.Task) {
+		value, ok = h.callback(task.Context(), sysreg, h.data)
+	})
+	return
+}
+
+func (h *tracerHandler) Close() error {
+	h.tracers.Delete(h)
+	return nil
+}
+
+func (h *symbolHook) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.releases) - 1; i >= 0; i-- {
+		h.releases[i]()
+	}
+	h.releases = nil
+	if h.hook != nil {
+		h.hook.Close()
+		h.hook = nil
+	}
+	h.module = nil
+	h.closed = true
+	return h.err
+}
+
+func (h *symbolHook) Type() emulator.HookType {
+	return h.typ
+}
+
+func (h *symbolHook) attach(module debugger.Module) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed || h.hook != nil {
+		return nil
+	}
+	addr, err := module.FindSymbol(h.name)
+	if err != nil {
+		return err
+	}
+	if h.dbg.Arch() == emulator.ARCH_ARM {
+		addr &^= 1
+	}
+	end := addr
+	if h.typ&(emulator.HOOK_TYPE_INTR|emulator.HOOK_TYPE_INSN_INVALID|emulator.HOOK_TYPE_MEM_INVALID) != 0 {
+		end++
+	}
+	hook, err := h.dbg.AddHook(h.typ, h.callback, h.data, addr, end)
+	if err != nil {
+		h.err = err
+		return err
+	}
+	h.module, h.hook, h.err = module, hook, nil
+	return nil
+}
+
+func (h *symbolHook) handleLoad(module debugger.Module) {
+	h.attach(module)
+}
+
+func (h *symbolHook) handleUnload(module debugger.Module) {
+	h.mu.Lock()
+	if h.module != module {
+		h.mu.Unlock()
+		return
+	}
+	h.hook.Close()
+	h.module, h.hook = nil, nil
+	h.mu.Unlock()
+	if module, _, err := h.dbg.FindSymbol(h.name); err == nil {
+		h.attach(module)
+	}
+}
+
 func (h *controlHandler) Close() error {
 	for i := len(h.releases) - 1; i >= 0; i-- {
 		h.releases[i]()
@@ -359,3 +506,28 @@ func (dbg *Dbg) AddHook(typ emulator.HookType, callback any, data any, begin, en
 func (dbg *Dbg) AddControl(callback debugger.ControlCallback, data any) (debugger.ControlHandler, error) {
 	return dbg.hookManger.addControl(dbg.impl, callback, data)
 }
+
+func (dbg *Dbg) AddSymbolHook(name string, typ emulator.HookType, callback any, data any) (debugger.HookHandler, error) {
+	return dbg.hookManger.addSymbolHook(dbg.impl, name, typ, callback, data)
+}
+
+func (dbg *Dbg) AddInterruptTracer(tracer debugger.InterruptTracer) io.Closer {
+	return dbg.hookManger.addInterruptTracer(tracer)
+}
+
+func checkCallback(typ emulator.HookType, callback any) bool {
+	var ok bool
+	switch typ {
+	case emulator.HOOK_TYPE_INTR:
+		_, ok = callback.(debugger.InterruptCallback)
+	case emulator.HOOK_TYPE_INSN_INVALID:
+		_, ok = callback.(debugger.InvalidCallback)
+	case emulator.HOOK_TYPE_CODE, emulator.HOOK_TYPE_BLOCK:
+		_, ok = callback.(debugger.CodeCallback)
+	case emulator.HOOK_TYPE_INSN_SYS:
+		_, ok = callback.(debugger.SysRegCallback)
+	default:
+		_, ok = callback.(debugger.MemoryCallback)
+	}
+	return ok
+}
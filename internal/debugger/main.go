@@ -53,6 +53,7 @@ func (m *mainTask) Close() error {
 	}
 	m.releases = nil
 	m.CancelCause(nil)
+	m.dbg.untrackTask(m)
 	m.updateStatus(debugger.TaskStatus_Close)
 	return nil
 }
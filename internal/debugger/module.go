@@ -1,74 +1,294 @@
 package debugger
 
 import (
+	"cmp"
+	"io"
 	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/wnxd/microdbg/debugger"
 )
 
+type moduleRegion struct {
+	begin, end uint64
+	module     debugger.Module
+}
+
+type symbolEntry struct {
+	module debugger.Module
+	addr   uint64
+}
+
+type moduleHandler struct {
+	callbacks *sync.Map
+	callback  debugger.ModuleCallback
+}
+
 type moduleManager struct {
-	mu     sync.Mutex
-	loaded []debugger.Module
+	mu       sync.RWMutex
+	loaded   []debugger.Module
+	regions  []moduleRegion
+	names    map[string]debugger.Module
+	bases    map[uint64]debugger.Module
+	gen      atomic.Uint64
+	cacheMu  sync.Mutex
+	exports  map[string]symbolEntry
+	symbols  map[debugger.Module][]debugger.Symbol
+	onLoad   sync.Map
+	onUnload sync.Map
 }
 
 func (mm *moduleManager) ctor() {
+	mm.names = make(map[string]debugger.Module)
+	mm.bases = make(map[uint64]debugger.Module)
+	mm.exports = make(map[string]symbolEntry)
+	mm.symbols = make(map[debugger.Module][]debugger.Symbol)
 }
 
 func (mm *moduleManager) dtor() {
-	for _, module := range mm.loaded {
+	mm.mu.Lock()
+	loaded := mm.loaded
+	mm.loaded = nil
+	mm.regions = nil
+	clear(mm.names)
+	clear(mm.bases)
+	mm.mu.Unlock()
+	mm.cacheMu.Lock()
+	clear(mm.exports)
+	clear(mm.symbols)
+	mm.cacheMu.Unlock()
+	for _, module := range loaded {
 		module.Close()
 	}
 }
 
 func (mm *moduleManager) Load(module debugger.Module) {
 	mm.mu.Lock()
-	if !slices.Contains(mm.loaded, module) {
-		mm.loaded = append(mm.loaded, module)
+	if slices.Contains(mm.loaded, module) {
+		mm.mu.Unlock()
+		return
+	}
+	mm.loaded = append(mm.loaded, module)
+	if begin, size := module.Region(); size != 0 {
+		region := moduleRegion{begin, begin + size, module}
+		i, _ := slices.BinarySearchFunc(mm.regions, begin, func(r moduleRegion, begin uint64) int {
+			return cmp.Compare(r.begin, begin)
+		})
+		mm.regions = slices.Insert(mm.regions, i, region)
+	}
+	if name := module.Name(); name != "" {
+		if _, ok := mm.names[name]; !ok {
+			mm.names[name] = module
+		}
+	}
+	if base := module.BaseAddr(); base != 0 {
+		if _, ok := mm.bases[base]; !ok {
+			mm.bases[base] = module
+		}
 	}
+	mm.gen.Add(1)
 	mm.mu.Unlock()
+	mm.cacheMu.Lock()
+	for name, entry := range mm.exports {
+		if entry.module == nil {
+			delete(mm.exports, name)
+		}
+	}
+	mm.cacheMu.Unlock()
+	mm.notify(&mm.onLoad, module)
 }
 
 func (mm *moduleManager) Unload(module debugger.Module) {
 	mm.mu.Lock()
-	mm.loaded = slices.DeleteFunc(mm.loaded, func(m debugger.Module) bool { return m == module })
+	if !slices.Contains(mm.loaded, module) {
+		mm.mu.Unlock()
+		return
+	}
+	mm.loaded = slices.DeleteFunc(slices.Clone(mm.loaded), func(m debugger.Module) bool { return m == module })
+	mm.regions = slices.DeleteFunc(mm.regions, func(r moduleRegion) bool { return r.module == module })
+	if name := module.Name(); mm.names[name] == module {
+		delete(mm.names, name)
+		for _, m := range mm.loaded {
+			if m.Name() == name {
+				mm.names[name] = m
+				break
+			}
+		}
+	}
+	if base := module.BaseAddr(); mm.bases[base] == module {
+		delete(mm.bases, base)
+		for _, m := range mm.loaded {
+			if m.BaseAddr() == base {
+				mm.bases[base] = m
+				break
+			}
+		}
+	}
+	mm.gen.Add(1)
 	mm.mu.Unlock()
+	mm.cacheMu.Lock()
+	for name, entry := range mm.exports {
+		if entry.module == module || entry.module == nil {
+			delete(mm.exports, name)
+		}
+	}
+	delete(mm.symbols, module)
+	mm.cacheMu.Unlock()
+	mm.notify(&mm.onUnload, module)
 }
 
 func (mm *moduleManager) FindModule(name string) (debugger.Module, error) {
-	for _, module := range mm.loaded {
-		if module.Name() == name {
-			return module, nil
-		}
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	if module, ok := mm.names[name]; ok {
+		return module, nil
 	}
 	return nil, debugger.ErrModuleNotFound
 }
 
 func (mm *moduleManager) FindModuleByAddr(addr uint64) (debugger.Module, error) {
-	for _, module := range mm.loaded {
-		begin, size := module.Region()
-		if addr >= begin && addr < begin+size {
-			return module, nil
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	i := sort.Search(len(mm.regions), func(i int) bool {
+		return mm.regions[i].begin > addr
+	})
+	for i--; i >= 0; i-- {
+		if addr < mm.regions[i].end {
+			return mm.regions[i].module, nil
 		}
 	}
 	return nil, debugger.ErrModuleNotFound
 }
 
 func (mm *moduleManager) FindSymbol(name string) (debugger.Module, uint64, error) {
-	for _, module := range mm.loaded {
+	mm.cacheMu.Lock()
+	entry, ok := mm.exports[name]
+	mm.cacheMu.Unlock()
+	if ok {
+		if entry.module == nil {
+			return nil, 0, debugger.ErrSymbolNotFound
+		}
+		return entry.module, entry.addr, nil
+	}
+	mm.mu.RLock()
+	loaded, gen := mm.loaded, mm.gen.Load()
+	mm.mu.RUnlock()
+	for _, module := range loaded {
 		addr, err := module.FindSymbol(name)
 		if err == nil {
-			return module, addr, err
+			entry = symbolEntry{module, addr}
+			break
+		}
+	}
+	mm.cacheMu.Lock()
+	if mm.gen.Load() == gen {
+		mm.exports[name] = entry
+	}
+	mm.cacheMu.Unlock()
+	if entry.module == nil {
+		return nil, 0, debugger.ErrSymbolNotFound
+	}
+	return entry.module, entry.addr, nil
+}
+
+func (mm *moduleManager) FindSymbolByAddr(addr uint64) (debugger.Module, debugger.Symbol, uint64, error) {
+	module, err := mm.FindModuleByAddr(addr)
+	if err != nil {
+		return nil, debugger.Symbol{}, 0, err
+	}
+	symbols := mm.sortedSymbols(module)
+	i, found := slices.BinarySearchFunc(symbols, addr, func(sym debugger.Symbol, addr uint64) int {
+		return cmp.Compare(sym.Value&^1, addr)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return module, debugger.Symbol{}, addr - module.BaseAddr(), debugger.ErrSymbolNotFound
+	}
+	sym := symbols[i]
+	return module, sym, addr - sym.Value&^1, nil
+}
+
+func (mm *moduleManager) Modules(yield func(debugger.Module) bool) {
+	mm.mu.RLock()
+	loaded := mm.loaded
+	mm.mu.RUnlock()
+	for _, module := range loaded {
+		if !yield(module) {
+			return
+		}
+	}
+}
+
+func (mm *moduleManager) Symbols(yield func(debugger.Module, debugger.Symbol) bool) {
+	mm.mu.RLock()
+	loaded := mm.loaded
+	mm.mu.RUnlock()
+	for _, module := range loaded {
+		iter, ok := module.(debugger.SymbolIter)
+		if !ok {
+			continue
+		}
+		for sym := range iter.Symbols {
+			if !yield(module, sym) {
+				return
+			}
 		}
 	}
-	return nil, 0, debugger.ErrSymbolNotFound
 }
 
 func (mm *moduleManager) GetModule(addr uint64) debugger.Module {
-	for _, module := range mm.loaded {
-		if module.BaseAddr() == addr {
-			return module
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	return mm.bases[addr]
+}
+
+func (mm *moduleManager) OnModuleLoad(callback debugger.ModuleCallback) io.Closer {
+	handler := &moduleHandler{callbacks: &mm.onLoad, callback: callback}
+	mm.onLoad.Store(handler, struct{}{})
+	return handler
+}
+
+func (mm *moduleManager) OnModuleUnload(callback debugger.ModuleCallback) io.Closer {
+	handler := &moduleHandler{callbacks: &mm.onUnload, callback: callback}
+	mm.onUnload.Store(handler, struct{}{})
+	return handler
+}
+
+func (mm *moduleManager) notify(callbacks *sync.Map, module debugger.Module) {
+	for handler := range callbacks.Range {
+		handler.(*moduleHandler).callback(module)
+	}
+}
+
+func (mm *moduleManager) sortedSymbols(module debugger.Module) []debugger.Symbol {
+	mm.cacheMu.Lock()
+	symbols, ok := mm.symbols[module]
+	mm.cacheMu.Unlock()
+	if ok {
+		return symbols
+	}
+	gen := mm.gen.Load()
+	if iter, ok := module.(debugger.SymbolIter); ok {
+		for sym := range iter.Symbols {
+			symbols = append(symbols, sym)
 		}
+		slices.SortStableFunc(symbols, func(a, b debugger.Symbol) int {
+			return cmp.Compare(a.Value&^1, b.Value&^1)
+		})
 	}
+	mm.cacheMu.Lock()
+	if mm.gen.Load() == gen {
+		mm.symbols[module] = symbols
+	}
+	mm.cacheMu.Unlock()
+	return symbols
+}
+
+func (h *moduleHandler) Close() error {
+	h.callbacks.Delete(h)
 	return nil
 }
@@ -48,6 +48,7 @@ func newRunner(ctx context.Context, tc *taskContext, dbg Debugger) *runner {
 	task.send = ch
 	go task.loop(task.ctx, ch)
 	task.change = true
+	dbg.trackTask(task)
 	return task
 }
 
@@ -58,6 +59,7 @@ func (r *runner) Close() error {
 	}
 	r.releases = nil
 	r.CancelCause(nil)
+	r.dbg.untrackTask(r)
 	r.dbg.freeTaskContext(r.taskCtx)
 	r.updateStatus(debugger.TaskStatus_Close)
 	return nil
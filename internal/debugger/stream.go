@@ -1,6 +1,8 @@
 package debugger
 
 import (
+	"errors"
+
 	"github.com/wnxd/microdbg/emulator"
 	"github.com/wnxd/microdbg/encoding"
 )
@@ -11,6 +13,14 @@ type pointerStream struct {
 	size  int
 }
 
+type bufferStream struct {
+	buf   *Buffer
+	off   int
+	mem   func(uint64) emulator.Pointer
+	alloc func(uint64) (emulator.Pointer, error)
+	size  int
+}
+
 func PointerStream(ptr emulator.Pointer, alloc func(uint64) (emulator.Pointer, error), size int) encoding.Stream {
 	return &pointerStream{ptr, alloc, size}
 }
@@ -97,3 +107,84 @@ func (ps *pointerStream) WriteStream(size int) (encoding.Stream, error) {
 	ps.Write(ToPtrRaw(&addr)[:ps.size])
 	return PointerStream(ptr, ps.alloc, ps.size), nil
 }
+
+func BufferStream(buf *Buffer, mem func(uint64) emulator.Pointer, alloc func(uint64) (emulator.Pointer, error), size int) encoding.Stream {
+	return &bufferStream{buf: buf, mem: mem, alloc: alloc, size: size}
+}
+
+func (bs *bufferStream) BlockSize() int {
+	return bs.size
+}
+
+func (bs *bufferStream) Offset() uint64 {
+	return 0
+}
+
+func (bs *bufferStream) Skip(n int) error {
+	bs.off += n
+	return nil
+}
+
+func (bs *bufferStream) Read(b []byte) (int, error) {
+	n, err := bs.buf.ReadAt(b, int64(bs.off))
+	bs.off += n
+	return n, err
+}
+
+func (bs *bufferStream) ReadFloat() (float32, error) {
+	var f float32
+	_, err := bs.Read(ToPtrRaw(&f))
+	return f, err
+}
+
+func (bs *bufferStream) ReadDouble() (float64, error) {
+	var d float64
+	_, err := bs.Read(ToPtrRaw(&d))
+	return d, err
+}
+
+func (bs *bufferStream) ReadString() (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (bs *bufferStream) ReadStream() (encoding.Stream, error) {
+	var addr uint64
+	_, err := bs.Read(ToPtrRaw(&addr)[:bs.size])
+	if err != nil {
+		return nil, err
+	}
+	return PointerStream(bs.mem(addr), bs.alloc, bs.size), nil
+}
+
+func (bs *bufferStream) Write(b []byte) (int, error) {
+	n, err := bs.buf.WriteAt(b, int64(bs.off))
+	bs.off += n
+	return n, err
+}
+
+func (bs *bufferStream) WriteFloat(f float32) error {
+	_, err := bs.Write(ToPtrRaw(&f))
+	return err
+}
+
+func (bs *bufferStream) WriteDouble(d float64) error {
+	_, err := bs.Write(ToPtrRaw(&d))
+	return err
+}
+
+func (bs *bufferStream) WriteString(string) error {
+	return errors.ErrUnsupported
+}
+
+func (bs *bufferStream) WriteStream(size int) (encoding.Stream, error) {
+	ptr, err := bs.alloc(uint64(size))
+	if err != nil {
+		return nil, err
+	}
+	addr := ptr.Address()
+	_, err = bs.Write(ToPtrRaw(&addr)[:bs.size])
+	if err != nil {
+		return nil, err
+	}
+	return PointerStream(ptr, bs.alloc, bs.size), nil
+}
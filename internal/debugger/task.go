@@ -4,6 +4,8 @@ import (
 	"context"
 	"math"
 	"runtime/debug"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 
@@ -36,6 +38,7 @@ type taskManager struct {
 	exec     chan func()
 	hasSync  bool
 	id       int64
+	tasks    sync.Map
 	main     *mainTask
 	current  task
 	emuerr   error
@@ -181,6 +184,24 @@ func (tm *taskManager) taskID() int {
 	return int(atomic.AddInt64(&tm.id, 1))
 }
 
+func (tm *taskManager) trackTask(task debugger.Task) {
+	tm.tasks.Store(task.ID(), task)
+}
+
+func (tm *taskManager) untrackTask(task debugger.Task) {
+	tm.tasks.CompareAndDelete(task.ID(), task)
+}
+
+func (tm *taskManager) allTasks() []debugger.Task {
+	var tasks []debugger.Task
+	tm.tasks.Range(func(key, value any) bool {
+		tasks = append(tasks, value.(debugger.Task))
+		return true
+	})
+	slices.SortFunc(tasks, func(a, b debugger.Task) int { return a.ID() - b.ID() })
+	return tasks
+}
+
 func (tm *taskManager) newTaskContext(dbg Debugger) (*taskContext, error) {
 	ctx, err := dbg.Emulator().ContextAlloc()
 	if err != nil {
@@ -226,6 +247,7 @@ func (tm *taskManager) getMainTask(ctx context.Context, dbg Debugger) (debugger.
 		return nil, debugger.TaskStatus_Running
 	}
 	tm.main.reset(ctx, dbg)
+	tm.trackTask(tm.main)
 	return tm.main, nil
 }
 
@@ -338,6 +360,10 @@ func (dbg *Dbg) CreateTask(ctx context.Context) (debugger.Task, error) {
 	return dbg.taskManager.createTask(ctx, dbg.impl)
 }
 
+func (dbg *Dbg) Tasks() []debugger.Task {
+	return dbg.taskManager.allTasks()
+}
+
 func (dbg *Dbg) CallTaskOf(t debugger.Task, addr uint64) error {
 	ctrl, err := dbg.impl.TaskControl(t, addr)
 	if err != nil {
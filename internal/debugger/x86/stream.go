@@ -0,0 +1,295 @@
+package x86
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"unsafe"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	"github.com/wnxd/microdbg/encoding"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+)
+
+var (
+	fastcallRegs = []emulator.Reg{emu_x86.X86_REG_ECX, emu_x86.X86_REG_EDX}
+	retRegs      = []emulator.Reg{emu_x86.X86_REG_EAX, emu_x86.X86_REG_EDX}
+)
+
+type regStream struct {
+	dbg   debugger.Debugger
+	ctx   debugger.RegisterContext
+	regs  []emulator.Reg
+	reg   bool
+	ret   bool
+	stoff int
+	groff int
+	value uint64
+	stack interface {
+		io.ReaderAt
+		io.WriterAt
+	}
+}
+
+func (rs *regStream) Next(size int, arg any) {
+	if rs.ret {
+		return
+	}
+	rs.reg = len(rs.regs) > 0 && size <= POINTER_SIZE && rs.groff < len(rs.regs)*POINTER_SIZE
+	if !rs.reg {
+		return
+	}
+	typ := reflect.TypeOf(arg)
+	if typ == nil {
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Struct, reflect.Array:
+		rs.reg = false
+	}
+}
+
+func (rs *regStream) Align() {
+	rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+	rs.groff = debugger.Align(rs.groff, POINTER_SIZE)
+}
+
+func (rs *regStream) BlockSize() int {
+	return POINTER_SIZE
+}
+
+func (rs *regStream) Offset() uint64 {
+	return 0
+}
+
+func (rs *regStream) Skip(n int) error {
+	if rs.reg {
+		rs.groff += n
+	} else {
+		rs.stoff += n
+	}
+	return nil
+}
+
+func (rs *regStream) Read(b []byte) (int, error) {
+	if !rs.reg {
+		return rs.readStack(b)
+	}
+	var i int
+	count := rs.groff / POINTER_SIZE
+	if i = rs.groff % POINTER_SIZE; i > 0 {
+		i = copy(b, internal.ToPtrRaw(&rs.value)[i:POINTER_SIZE])
+		rs.groff += i
+		count++
+	}
+	for i < len(b) {
+		if count >= len(rs.regs) {
+			n, err := rs.readStack(b[i:])
+			return i + n, err
+		}
+		var err error
+		rs.value, err = rs.ctx.RegRead(rs.regs[count])
+		if err != nil {
+			return i, err
+		}
+		n := copy(b[i:], internal.ToPtrRaw(&rs.value)[:POINTER_SIZE])
+		i += n
+		rs.groff += n
+		count++
+	}
+	return i, nil
+}
+
+func (rs *regStream) ReadFloat() (float32, error) {
+	if rs.ret {
+		d, err := rs.readST0()
+		return float32(d), err
+	}
+	var f float32
+	_, err := rs.readStack(internal.ToPtrRaw(&f))
+	return f, err
+}
+
+func (rs *regStream) ReadDouble() (float64, error) {
+	if rs.ret {
+		return rs.readST0()
+	}
+	var d float64
+	_, err := rs.readStack(internal.ToPtrRaw(&d))
+	return d, err
+}
+
+func (rs *regStream) ReadString() (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (rs *regStream) ReadStream() (encoding.Stream, error) {
+	var addr uint32
+	_, err := rs.Read(internal.ToPtrRaw(&addr))
+	if err != nil {
+		return nil, err
+	}
+	return internal.PointerStream(rs.dbg.ToPointer(uint64(addr)), rs.ctx.StackAlloc, POINTER_SIZE), nil
+}
+
+func (rs *regStream) Write(b []byte) (int, error) {
+	if !rs.reg {
+		return rs.writeStack(b)
+	}
+	var i int
+	count := rs.groff / POINTER_SIZE
+	if i = rs.groff % POINTER_SIZE; i > 0 {
+		i = copy(internal.ToPtrRaw(&rs.value)[i:POINTER_SIZE], b)
+		err := rs.ctx.RegWrite(rs.regs[count], uint64(uint32(rs.value)))
+		if err != nil {
+			return 0, err
+		}
+		rs.groff += i
+		count++
+	}
+	for i < len(b) {
+		if count >= len(rs.regs) {
+			n, err := rs.writeStack(b[i:])
+			return i + n, err
+		}
+		rs.value = 0
+		n := copy(internal.ToPtrRaw(&rs.value)[:POINTER_SIZE], b[i:])
+		err := rs.ctx.RegWrite(rs.regs[count], rs.value)
+		if err != nil {
+			return i, err
+		}
+		i += n
+		rs.groff += n
+		count++
+	}
+	return i, nil
+}
+
+func (rs *regStream) WriteFloat(f float32) error {
+	if rs.ret {
+		return rs.pushST0(float64(f))
+	}
+	_, err := rs.writeStack(internal.ToPtrRaw(&f))
+	return err
+}
+
+func (rs *regStream) WriteDouble(d float64) error {
+	if rs.ret {
+		return rs.pushST0(d)
+	}
+	_, err := rs.writeStack(internal.ToPtrRaw(&d))
+	return err
+}
+
+func (rs *regStream) WriteString(string) error {
+	return errors.ErrUnsupported
+}
+
+func (rs *regStream) WriteStream(size int) (encoding.Stream, error) {
+	ptr, err := rs.ctx.StackAlloc(uint64(size))
+	if err != nil {
+		return nil, err
+	}
+	addr := uint32(ptr.Address())
+	_, err = rs.Write(internal.ToPtrRaw(&addr))
+	if err != nil {
+		return nil, err
+	}
+	return internal.PointerStream(ptr, rs.ctx.StackAlloc, POINTER_SIZE), nil
+}
+
+func (rs *regStream) readStack(b []byte) (int, error) {
+	if rs.stack == nil {
+		return 0, errors.ErrUnsupported
+	}
+	n, err := rs.stack.ReadAt(b, int64(rs.stoff))
+	rs.stoff += n
+	return n, err
+}
+
+func (rs *regStream) writeStack(b []byte) (int, error) {
+	if rs.stack == nil {
+		return 0, errors.ErrUnsupported
+	}
+	n, err := rs.stack.WriteAt(b, int64(rs.stoff))
+	rs.stoff += n
+	return n, err
+}
+
+func (rs *regStream) readST0() (float64, error) {
+	var raw [10]byte
+	err := rs.ctx.RegReadPtr(emu_x86.X86_REG_ST0, unsafe.Pointer(&raw))
+	if err != nil {
+		return 0, err
+	}
+	return fromExtended(raw), nil
+}
+
+func (rs *regStream) pushST0(d float64) error {
+	fpsw, err := rs.ctx.RegRead(emu_x86.X86_REG_FPSW)
+	if err != nil {
+		return err
+	}
+	top := (fpsw>>11 - 1) & 7
+	err = rs.ctx.RegWrite(emu_x86.X86_REG_FPSW, fpsw&^0x3800|top<<11)
+	if err != nil {
+		return err
+	}
+	raw := toExtended(d)
+	err = rs.ctx.RegWritePtr(emu_x86.X86_REG_ST0, unsafe.Pointer(&raw))
+	if err != nil {
+		return err
+	}
+	tag, err := rs.ctx.RegRead(emu_x86.X86_REG_FPTAG)
+	if err != nil {
+		return err
+	}
+	return rs.ctx.RegWrite(emu_x86.X86_REG_FPTAG, tag&^(3<<(top*2)))
+}
+
+func fromExtended(raw [10]byte) float64 {
+	mant := binary.LittleEndian.Uint64(raw[:8])
+	se := binary.LittleEndian.Uint16(raw[8:])
+	sign, exp := se>>15 != 0, int(se&0x7fff)
+	var d float64
+	switch {
+	case exp == 0x7fff && mant<<1 == 0:
+		d = math.Inf(1)
+	case exp == 0x7fff:
+		d = math.NaN()
+	default:
+		d = math.Ldexp(float64(mant), exp-16383-63)
+	}
+	if sign {
+		d = -d
+	}
+	return d
+}
+
+func toExtended(d float64) (raw [10]byte) {
+	bits := math.Float64bits(d)
+	se := uint16(bits >> 63 << 15)
+	exp := int(bits >> 52 & 0x7ff)
+	frac := bits & (1<<52 - 1)
+	var mant uint64
+	switch {
+	case exp == 0x7ff:
+		se |= 0x7fff
+		mant = 1<<63 | frac<<11
+	case exp == 0 && frac == 0:
+	case exp == 0:
+		frac, e := math.Frexp(math.Abs(d))
+		se |= uint16(e - 1 + 16383)
+		mant = uint64(math.Ldexp(frac, 64))
+	default:
+		se |= uint16(exp - 1023 + 16383)
+		mant = 1<<63 | frac<<11
+	}
+	binary.LittleEndian.PutUint64(raw[:8], mant)
+	binary.LittleEndian.PutUint16(raw[8:], se)
+	return
+}
@@ -0,0 +1,191 @@
+package x86
+
+import (
+	"encoding/binary"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	"github.com/wnxd/microdbg/encoding"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+)
+
+const (
+	X86_STACK_SIZE = 10 * 0x1000
+	POINTER_SIZE   = 4
+)
+
+type X86Dbg struct {
+	internal.Dbg
+}
+
+func NewX86Debugger(emu emulator.Emulator) (debugger.Debugger, error) {
+	dbg := new(X86Dbg)
+	err := dbg.Init(dbg, emu)
+	if err != nil {
+		return nil, err
+	}
+	return dbg, nil
+}
+
+func (dbg *X86Dbg) Init(impl internal.Debugger, emu emulator.Emulator) error {
+	return dbg.Dbg.Init(impl, emu)
+}
+
+func (dbg *X86Dbg) Close() error {
+	return dbg.Dbg.Close()
+}
+
+func (dbg *X86Dbg) Arch() emulator.Arch {
+	return emulator.ARCH_X86
+}
+
+func (dbg *X86Dbg) PointerSize() uint64 {
+	return POINTER_SIZE
+}
+
+func (dbg *X86Dbg) StackSize() uint64 {
+	return X86_STACK_SIZE
+}
+
+func (dbg *X86Dbg) StackAlign() uint64 {
+	return 16
+}
+
+func (dbg *X86Dbg) PC() emulator.Reg {
+	return emu_x86.X86_REG_EIP
+}
+
+func (dbg *X86Dbg) SP() emulator.Reg {
+	return emu_x86.X86_REG_ESP
+}
+
+func (dbg *X86Dbg) Args(ctx debugger.RegisterContext, calling debugger.Calling) (debugger.Args, error) {
+	regs, err := argRegs(calling)
+	if err != nil {
+		return nil, err
+	}
+	stackAddr, err := ctx.RegRead(emu_x86.X86_REG_ESP)
+	if err != nil {
+		return nil, err
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, regs: regs, stack: dbg.ToPointer(stackAddr + POINTER_SIZE)}
+	return internal.Args(func(args ...any) error {
+		for _, arg := range args {
+			stream.Next(encoding.DecodeSize(POINTER_SIZE, arg), arg)
+			err := encoding.Decode(stream, arg)
+			if err != nil {
+				return err
+			}
+			stream.Align()
+		}
+		return nil
+	}), nil
+}
+
+func (dbg *X86Dbg) ArgWrite(ctx debugger.RegisterContext, calling debugger.Calling, args ...any) error {
+	regs, err := argRegs(calling)
+	if err != nil {
+		return err
+	}
+	var buf internal.Buffer
+	stream := &regStream{dbg: dbg, ctx: ctx, regs: regs, stack: &buf}
+	for _, arg := range args {
+		stream.Next(encoding.EncodeSize(POINTER_SIZE, arg), arg)
+		err := encoding.Encode(stream, arg)
+		if err != nil {
+			return err
+		}
+		stream.Align()
+	}
+	if stream.stoff == 0 {
+		return nil
+	}
+	ptr, err := ctx.StackAlloc(uint64(stream.stoff))
+	if err != nil {
+		return err
+	}
+	return ptr.MemWrite(buf)
+}
+
+func (dbg *X86Dbg) RetExtract(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
+	if internal.GetPtr(val) == nil {
+		return debugger.ErrArgumentInvalid
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, regs: retRegs, reg: true, ret: true}
+	return encoding.Decode(stream, val)
+}
+
+func (dbg *X86Dbg) RetWrite(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
+	if internal.GetPtr(val) == nil {
+		return ctx.RegWrite(emu_x86.X86_REG_EAX, 0)
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, regs: retRegs, reg: true, ret: true}
+	return encoding.Encode(stream, val)
+}
+
+func (dbg *X86Dbg) Return(ctx debugger.RegisterContext) error {
+	sp, err := ctx.RegRead(emu_x86.X86_REG_ESP)
+	if err != nil {
+		return err
+	}
+	buf, err := dbg.ToPointer(sp).MemRead(POINTER_SIZE)
+	if err != nil {
+		return err
+	}
+	err = ctx.RegWrite(emu_x86.X86_REG_ESP, sp+POINTER_SIZE)
+	if err != nil {
+		return err
+	}
+	return ctx.RegWrite(emu_x86.X86_REG_EIP, uint64(binary.LittleEndian.Uint32(buf)))
+}
+
+func (dbg *X86Dbg) InitStack() (uint64, error) {
+	region, err := dbg.MapAlloc(X86_STACK_SIZE, emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)
+	if err != nil {
+		return 0, err
+	}
+	stack := region.Addr + X86_STACK_SIZE
+	return stack, nil
+}
+
+func (dbg *X86Dbg) CloseStack(stack uint64) error {
+	begin := stack - X86_STACK_SIZE
+	return dbg.MapFree(begin, X86_STACK_SIZE)
+}
+
+func (dbg *X86Dbg) TaskControl(task debugger.Task, addr uint64) (debugger.ControlHandler, error) {
+	ctrl, err := dbg.AddControl(func(ctx debugger.Context, data any) {
+		task := data.(debugger.Task)
+		if task.Context() != ctx {
+			panic("call exception return")
+		}
+		task.CancelCause(debugger.TaskStatus_Done)
+	}, task)
+	if err != nil {
+		return nil, err
+	}
+	ctx := task.Context()
+	sp, err := ctx.RegRead(emu_x86.X86_REG_ESP)
+	if err == nil {
+		sp -= POINTER_SIZE
+		err = dbg.ToPointer(sp).MemWrite(binary.LittleEndian.AppendUint32(nil, uint32(ctrl.Addr())))
+	}
+	if err != nil {
+		ctrl.Close()
+		return nil, err
+	}
+	ctx.RegWrite(emu_x86.X86_REG_ESP, sp)
+	ctx.RegWrite(emu_x86.X86_REG_EIP, addr)
+	return ctrl, nil
+}
+
+func argRegs(calling debugger.Calling) ([]emulator.Reg, error) {
+	switch calling {
+	case debugger.Calling_Default, debugger.Calling_Cdecl, debugger.Calling_Stdcall:
+		return nil, nil
+	case debugger.Calling_Fastcall:
+		return fastcallRegs, nil
+	}
+	return nil, debugger.ErrCallingUnsupported
+}
@@ -0,0 +1,271 @@
+package x86_64
+
+import (
+	"encoding/binary"
+	"reflect"
+
+	"github.com/wnxd/microdbg/debugger"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	"github.com/wnxd/microdbg/encoding"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+)
+
+type class int
+
+const (
+	classNone class = iota
+	classInteger
+	classSSE
+)
+
+func classify(typ reflect.Type) (int, bool) {
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Array:
+		return encoding.DecodeSize(POINTER_SIZE, reflect.New(typ).Interface()), true
+	}
+	return 0, false
+}
+
+func byValue(size int) bool {
+	switch size {
+	case 1, 2, 4, 8:
+		return true
+	}
+	return false
+}
+
+func eightbytes(typ reflect.Type, size int) ([]class, bool) {
+	if size == 0 || size > 2*POINTER_SIZE {
+		return nil, false
+	}
+	classes := make([]class, (size+POINTER_SIZE-1)/POINTER_SIZE)
+	if _, ok := layout(typ, 0, classes); !ok {
+		return nil, false
+	}
+	for i, c := range classes {
+		if c == classNone {
+			classes[i] = classSSE
+		}
+	}
+	return classes, true
+}
+
+func layout(typ reflect.Type, offset int, classes []class) (int, bool) {
+	offset = debugger.Align(offset, typ.Align())
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			if field.Tag.Get("encoding") == "ignore" {
+				continue
+			}
+			var ok bool
+			if offset, ok = layout(field.Type, offset, classes); !ok {
+				return 0, false
+			}
+		}
+		return debugger.Align(offset, typ.Align()), true
+	case reflect.Array:
+		for range typ.Len() {
+			var ok bool
+			if offset, ok = layout(typ.Elem(), offset, classes); !ok {
+				return 0, false
+			}
+		}
+		return offset, true
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return mark(classes, offset, int(typ.Size()), classSSE)
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mark(classes, offset, int(typ.Size()), classInteger)
+	}
+	return mark(classes, debugger.Align(offset, POINTER_SIZE), POINTER_SIZE, classInteger)
+}
+
+func mark(classes []class, offset, size int, c class) (int, bool) {
+	end := offset + size
+	if end > len(classes)*POINTER_SIZE {
+		return 0, false
+	}
+	for i := offset / POINTER_SIZE; i < (end+POINTER_SIZE-1)/POINTER_SIZE; i++ {
+		if c == classInteger || classes[i] == classNone {
+			classes[i] = c
+		}
+	}
+	return end, true
+}
+
+func (rs *regStream) encode(val any) error {
+	if result, ok := val.(debugger.IndirectResult); ok {
+		return rs.indirect(result.Val)
+	} else if internal.GetPtr(val) == nil {
+		return encoding.Encode(rs, val)
+	}
+	typ := reflect.TypeOf(val)
+	size, ok := classify(typ)
+	if !ok {
+		return encoding.Encode(rs, val)
+	}
+	buf := make(internal.Buffer, size)
+	err := encoding.Encode(rs.buffer(&buf), val)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, make(internal.Buffer, debugger.Align(size, POINTER_SIZE)-size)...)
+	if rs.abi.byRef {
+		return rs.encodeWin64(buf, size)
+	}
+	return rs.encodeSysV(typ, buf, size)
+}
+
+func (rs *regStream) decode(val any) error {
+	typ := reflect.TypeOf(val)
+	if internal.GetPtr(val) == nil || typ.Kind() != reflect.Pointer {
+		return encoding.Decode(rs, val)
+	}
+	size, ok := classify(typ.Elem())
+	if !ok {
+		return encoding.Decode(rs, val)
+	} else if rs.abi.byRef {
+		return rs.decodeWin64(val, size)
+	}
+	return rs.decodeSysV(val, typ.Elem(), size)
+}
+
+func (rs *regStream) encodeWin64(buf internal.Buffer, size int) error {
+	if byValue(size) {
+		_, err := rs.Write(buf)
+		return err
+	} else if rs.ret {
+		return rs.writeIndirect(buf[:size])
+	}
+	ptr, err := rs.ctx.StackAlloc(uint64(size))
+	if err != nil {
+		return err
+	}
+	err = ptr.MemWrite(buf[:size])
+	if err != nil {
+		return err
+	}
+	addr := ptr.Address()
+	_, err = rs.Write(internal.ToPtrRaw(&addr))
+	return err
+}
+
+func (rs *regStream) decodeWin64(val any, size int) error {
+	if byValue(size) {
+		buf := make(internal.Buffer, POINTER_SIZE)
+		_, err := rs.Read(buf)
+		if err != nil {
+			return err
+		}
+		return encoding.Decode(rs.buffer(&buf), val)
+	}
+	var addr uint64
+	var err error
+	if rs.ret {
+		addr, err = rs.ctx.RegRead(emu_x86.X86_REG_RAX)
+	} else {
+		_, err = rs.Read(internal.ToPtrRaw(&addr))
+	}
+	if err != nil {
+		return err
+	}
+	return encoding.Decode(internal.PointerStream(rs.dbg.ToPointer(addr), rs.ctx.StackAlloc, POINTER_SIZE), val)
+}
+
+func (rs *regStream) encodeSysV(typ reflect.Type, buf internal.Buffer, size int) error {
+	classes, ok := eightbytes(typ, size)
+	if ok && rs.fits(classes) {
+		for i, c := range classes {
+			chunk := buf[i*POINTER_SIZE : (i+1)*POINTER_SIZE]
+			var err error
+			if c == classSSE {
+				err = rs.writeVector(binary.LittleEndian.Uint64(chunk), chunk)
+			} else {
+				_, err = rs.Write(chunk)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	} else if rs.ret {
+		return rs.writeIndirect(buf[:size])
+	}
+	_, err := rs.writeStack(buf)
+	return err
+}
+
+func (rs *regStream) decodeSysV(val any, typ reflect.Type, size int) error {
+	classes, ok := eightbytes(typ, size)
+	if ok && rs.fits(classes) {
+		buf := make(internal.Buffer, len(classes)*POINTER_SIZE)
+		for i, c := range classes {
+			chunk := buf[i*POINTER_SIZE : (i+1)*POINTER_SIZE]
+			var err error
+			if c == classSSE {
+				var d float64
+				d, err = rs.ReadDouble()
+				copy(chunk, internal.ToPtrRaw(&d))
+			} else {
+				_, err = rs.Read(chunk)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return encoding.Decode(rs.buffer(&buf), val)
+	} else if rs.ret {
+		addr, err := rs.ctx.RegRead(emu_x86.X86_REG_RAX)
+		if err != nil {
+			return err
+		}
+		return encoding.Decode(internal.PointerStream(rs.dbg.ToPointer(addr), rs.ctx.StackAlloc, POINTER_SIZE), val)
+	}
+	buf := make(internal.Buffer, debugger.Align(size, POINTER_SIZE))
+	_, err := rs.readStack(buf)
+	if err != nil {
+		return err
+	}
+	return encoding.Decode(rs.buffer(&buf), val)
+}
+
+func (rs *regStream) fits(classes []class) bool {
+	var gp, vr int
+	for _, c := range classes {
+		if c == classSSE {
+			vr++
+		} else {
+			gp++
+		}
+	}
+	return rs.groff/POINTER_SIZE+gp <= len(rs.abi.gp) && rs.vroff+vr <= len(rs.abi.vr)
+}
+
+func (rs *regStream) writeIndirect(buf internal.Buffer) error {
+	addr, err := rs.ctx.RegRead(rs.abi.indirect)
+	if err != nil {
+		return err
+	}
+	err = rs.dbg.ToPointer(addr).MemWrite(buf)
+	if err != nil {
+		return err
+	}
+	return rs.ctx.RegWrite(emu_x86.X86_REG_RAX, addr)
+}
+
+func (rs *regStream) indirect(val any) error {
+	size := encoding.DecodeSize(POINTER_SIZE, val)
+	ptr, err := rs.ctx.StackAlloc(uint64(size))
+	if err != nil {
+		return err
+	}
+	addr := ptr.Address()
+	_, err = rs.Write(internal.ToPtrRaw(&addr))
+	return err
+}
+
+func (rs *regStream) buffer(buf *internal.Buffer) encoding.Stream {
+	return internal.BufferStream(buf, rs.dbg.ToPointer, rs.ctx.StackAlloc, POINTER_SIZE)
+}
@@ -0,0 +1,287 @@
+package x86_64
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"unsafe"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	"github.com/wnxd/microdbg/encoding"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+)
+
+type abi struct {
+	gp         []emulator.Reg
+	vr         []emulator.Reg
+	indirect   emulator.Reg
+	positional bool
+	byRef      bool
+	shadow     int
+}
+
+var (
+	sysvABI = &abi{
+		gp: []emulator.Reg{emu_x86.X86_REG_RDI, emu_x86.X86_REG_RSI, emu_x86.X86_REG_RDX, emu_x86.X86_REG_RCX, emu_x86.X86_REG_R8, emu_x86.X86_REG_R9},
+		vr: []emulator.Reg{emu_x86.X86_REG_XMM0, emu_x86.X86_REG_XMM1, emu_x86.X86_REG_XMM2, emu_x86.X86_REG_XMM3, emu_x86.X86_REG_XMM4, emu_x86.X86_REG_XMM5, emu_x86.X86_REG_XMM6, emu_x86.X86_REG_XMM7},
+	}
+	win64ABI = &abi{
+		gp:         []emulator.Reg{emu_x86.X86_REG_RCX, emu_x86.X86_REG_RDX, emu_x86.X86_REG_R8, emu_x86.X86_REG_R9},
+		vr:         []emulator.Reg{emu_x86.X86_REG_XMM0, emu_x86.X86_REG_XMM1, emu_x86.X86_REG_XMM2, emu_x86.X86_REG_XMM3},
+		positional: true,
+		byRef:      true,
+		shadow:     4 * POINTER_SIZE,
+	}
+	sysvRetABI = &abi{
+		gp:       []emulator.Reg{emu_x86.X86_REG_RAX, emu_x86.X86_REG_RDX},
+		vr:       []emulator.Reg{emu_x86.X86_REG_XMM0, emu_x86.X86_REG_XMM1},
+		indirect: emu_x86.X86_REG_RDI,
+	}
+	win64RetABI = &abi{
+		gp:       []emulator.Reg{emu_x86.X86_REG_RAX},
+		vr:       []emulator.Reg{emu_x86.X86_REG_XMM0},
+		indirect: emu_x86.X86_REG_RCX,
+		byRef:    true,
+	}
+)
+
+type regStream struct {
+	dbg   debugger.Debugger
+	ctx   debugger.RegisterContext
+	abi   *abi
+	ret   bool
+	stoff int
+	groff int
+	vroff int
+	value uint64
+	stack interface {
+		io.ReaderAt
+		io.WriterAt
+	}
+}
+
+func getABI(calling debugger.Calling) (*abi, error) {
+	switch calling {
+	case debugger.Calling_Default, debugger.Calling_SysV:
+		return sysvABI, nil
+	case debugger.Calling_Win64:
+		return win64ABI, nil
+	}
+	return nil, debugger.ErrCallingUnsupported
+}
+
+func getRetABI(calling debugger.Calling) (*abi, error) {
+	switch calling {
+	case debugger.Calling_Default, debugger.Calling_SysV:
+		return sysvRetABI, nil
+	case debugger.Calling_Win64:
+		return win64RetABI, nil
+	}
+	return nil, debugger.ErrCallingUnsupported
+}
+
+func (rs *regStream) Align() {
+	rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+	rs.groff = debugger.Align(rs.groff, POINTER_SIZE)
+}
+
+func (rs *regStream) BlockSize() int {
+	return POINTER_SIZE
+}
+
+func (rs *regStream) Offset() uint64 {
+	return 0
+}
+
+func (rs *regStream) Skip(n int) error {
+	if rs.groff < len(rs.abi.gp)*POINTER_SIZE {
+		rs.groff += n
+	} else {
+		rs.stoff += n
+	}
+	return nil
+}
+
+func (rs *regStream) Read(b []byte) (int, error) {
+	if rs.groff >= len(rs.abi.gp)*POINTER_SIZE {
+		return rs.readStack(b)
+	}
+	var i int
+	count := rs.groff / POINTER_SIZE
+	if i = rs.groff % POINTER_SIZE; i > 0 {
+		i = copy(b, internal.ToPtrRaw(&rs.value)[i:])
+		rs.groff += i
+		count++
+	}
+	for i < len(b) {
+		if rs.groff >= len(rs.abi.gp)*POINTER_SIZE {
+			n, err := rs.readStack(b[i:])
+			return i + n, err
+		}
+		var err error
+		rs.value, err = rs.ctx.RegRead(rs.abi.gp[count])
+		if err != nil {
+			return i, err
+		}
+		n := copy(b[i:], internal.ToPtrRaw(&rs.value))
+		i += n
+		rs.groff += n
+		count++
+	}
+	return i, nil
+}
+
+func (rs *regStream) ReadFloat() (float32, error) {
+	var f float32
+	reg, ok := rs.nextVector()
+	if !ok {
+		_, err := rs.readStack(internal.ToPtrRaw(&f))
+		rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+		return f, err
+	}
+	var xmm [16]byte
+	err := rs.ctx.RegReadPtr(reg, unsafe.Pointer(&xmm))
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(xmm[:])), nil
+}
+
+func (rs *regStream) ReadDouble() (float64, error) {
+	var d float64
+	reg, ok := rs.nextVector()
+	if !ok {
+		_, err := rs.readStack(internal.ToPtrRaw(&d))
+		return d, err
+	}
+	var xmm [16]byte
+	err := rs.ctx.RegReadPtr(reg, unsafe.Pointer(&xmm))
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(xmm[:])), nil
+}
+
+func (rs *regStream) ReadString() (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (rs *regStream) ReadStream() (encoding.Stream, error) {
+	var addr uint64
+	_, err := rs.Read(internal.ToPtrRaw(&addr))
+	if err != nil {
+		return nil, err
+	}
+	return internal.PointerStream(rs.dbg.ToPointer(addr), rs.ctx.StackAlloc, POINTER_SIZE), nil
+}
+
+func (rs *regStream) Write(b []byte) (int, error) {
+	if rs.groff >= len(rs.abi.gp)*POINTER_SIZE {
+		return rs.writeStack(b)
+	}
+	var i int
+	count := rs.groff / POINTER_SIZE
+	if i = rs.groff % POINTER_SIZE; i > 0 {
+		i = copy(internal.ToPtrRaw(&rs.value)[i:], b)
+		err := rs.ctx.RegWrite(rs.abi.gp[count], rs.value)
+		if err != nil {
+			return 0, err
+		}
+		rs.groff += i
+		count++
+	}
+	for i < len(b) {
+		if rs.groff >= len(rs.abi.gp)*POINTER_SIZE {
+			n, err := rs.writeStack(b[i:])
+			return i + n, err
+		}
+		rs.value = 0
+		n := copy(internal.ToPtrRaw(&rs.value), b[i:])
+		err := rs.ctx.RegWrite(rs.abi.gp[count], rs.value)
+		if err != nil {
+			return i, err
+		}
+		i += n
+		rs.groff += n
+		count++
+	}
+	return i, nil
+}
+
+func (rs *regStream) WriteFloat(f float32) error {
+	return rs.writeVector(uint64(math.Float32bits(f)), internal.ToPtrRaw(&f))
+}
+
+func (rs *regStream) WriteDouble(d float64) error {
+	return rs.writeVector(math.Float64bits(d), internal.ToPtrRaw(&d))
+}
+
+func (rs *regStream) WriteString(string) error {
+	return errors.ErrUnsupported
+}
+
+func (rs *regStream) WriteStream(size int) (encoding.Stream, error) {
+	ptr, err := rs.ctx.StackAlloc(uint64(size))
+	if err != nil {
+		return nil, err
+	}
+	addr := ptr.Address()
+	_, err = rs.Write(internal.ToPtrRaw(&addr))
+	if err != nil {
+		return nil, err
+	}
+	return internal.PointerStream(ptr, rs.ctx.StackAlloc, POINTER_SIZE), nil
+}
+
+func (rs *regStream) nextVector() (emulator.Reg, bool) {
+	index := rs.vroff
+	if rs.abi.positional {
+		index = rs.groff / POINTER_SIZE
+	}
+	if index >= len(rs.abi.vr) {
+		return 0, false
+	}
+	if rs.abi.positional {
+		rs.groff += POINTER_SIZE
+	}
+	rs.vroff++
+	return rs.abi.vr[index], true
+}
+
+func (rs *regStream) writeVector(bits uint64, raw []byte) error {
+	slot := rs.groff / POINTER_SIZE
+	reg, ok := rs.nextVector()
+	if !ok {
+		_, err := rs.writeStack(raw)
+		rs.stoff = debugger.Align(rs.stoff, POINTER_SIZE)
+		return err
+	}
+	var xmm [16]byte
+	binary.LittleEndian.PutUint64(xmm[:], bits)
+	err := rs.ctx.RegWritePtr(reg, unsafe.Pointer(&xmm))
+	if err != nil || !rs.abi.positional {
+		return err
+	}
+	return rs.ctx.RegWrite(rs.abi.gp[slot], bits)
+}
+
+func (rs *regStream) readStack(b []byte) (int, error) {
+	if rs.stack == nil {
+		return 0, errors.ErrUnsupported
+	}
+	n, err := rs.stack.ReadAt(b, int64(rs.stoff))
+	rs.stoff += n
+	return n, err
+}
+
+func (rs *regStream) writeStack(b []byte) (int, error) {
+	if rs.stack == nil {
+		return 0, errors.ErrUnsupported
+	}
+	n, err := rs.stack.WriteAt(b, int64(rs.stoff))
+	rs.stoff += n
+	return n, err
+}
@@ -0,0 +1,191 @@
+package x86_64
+
+import (
+	"encoding/binary"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+	internal "github.com/wnxd/microdbg/internal/debugger"
+	inter_x86 "github.com/wnxd/microdbg/internal/debugger/x86"
+)
+
+const (
+	X86_64_STACK_SIZE = inter_x86.X86_STACK_SIZE * 2
+	POINTER_SIZE      = 8
+)
+
+type X86_64Dbg struct {
+	internal.Dbg
+}
+
+func NewX86_64Debugger(emu emulator.Emulator) (debugger.Debugger, error) {
+	dbg := new(X86_64Dbg)
+	err := dbg.Init(dbg, emu)
+	if err != nil {
+		return nil, err
+	}
+	return dbg, nil
+}
+
+func (dbg *X86_64Dbg) Init(impl internal.Debugger, emu emulator.Emulator) error {
+	return dbg.Dbg.Init(impl, emu)
+}
+
+func (dbg *X86_64Dbg) Close() error {
+	return dbg.Dbg.Close()
+}
+
+func (dbg *X86_64Dbg) Arch() emulator.Arch {
+	return emulator.ARCH_X86_64
+}
+
+func (dbg *X86_64Dbg) PointerSize() uint64 {
+	return POINTER_SIZE
+}
+
+func (dbg *X86_64Dbg) StackSize() uint64 {
+	return X86_64_STACK_SIZE
+}
+
+func (dbg *X86_64Dbg) StackAlign() uint64 {
+	return 16
+}
+
+func (dbg *X86_64Dbg) PC() emulator.Reg {
+	return emu_x86.X86_REG_RIP
+}
+
+func (dbg *X86_64Dbg) SP() emulator.Reg {
+	return emu_x86.X86_REG_RSP
+}
+
+func (dbg *X86_64Dbg) Args(ctx debugger.RegisterContext, calling debugger.Calling) (debugger.Args, error) {
+	abi, err := getABI(calling)
+	if err != nil {
+		return nil, err
+	}
+	stackAddr, err := ctx.RegRead(emu_x86.X86_REG_RSP)
+	if err != nil {
+		return nil, err
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, abi: abi, stoff: abi.shadow, stack: dbg.ToPointer(stackAddr + POINTER_SIZE)}
+	return internal.Args(func(args ...any) error {
+		for _, arg := range args {
+			err := stream.decode(arg)
+			if err != nil {
+				return err
+			}
+			stream.Align()
+		}
+		return nil
+	}), nil
+}
+
+func (dbg *X86_64Dbg) ArgWrite(ctx debugger.RegisterContext, calling debugger.Calling, args ...any) error {
+	abi, err := getABI(calling)
+	if err != nil {
+		return err
+	}
+	buf := make(internal.Buffer, abi.shadow)
+	stream := &regStream{dbg: dbg, ctx: ctx, abi: abi, stoff: abi.shadow, stack: &buf}
+	for _, arg := range args {
+		err := stream.encode(arg)
+		if err != nil {
+			return err
+		}
+		stream.Align()
+	}
+	if !abi.positional {
+		err = ctx.RegWrite(emu_x86.X86_REG_RAX, uint64(stream.vroff))
+		if err != nil {
+			return err
+		}
+	}
+	if stream.stoff == 0 {
+		return nil
+	}
+	ptr, err := ctx.StackAlloc(uint64(stream.stoff))
+	if err != nil {
+		return err
+	}
+	return ptr.MemWrite(buf)
+}
+
+func (dbg *X86_64Dbg) RetExtract(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
+	abi, err := getRetABI(calling)
+	if err != nil {
+		return err
+	} else if internal.GetPtr(val) == nil {
+		return debugger.ErrArgumentInvalid
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, abi: abi, ret: true}
+	return stream.decode(val)
+}
+
+func (dbg *X86_64Dbg) RetWrite(ctx debugger.RegisterContext, calling debugger.Calling, val any) error {
+	abi, err := getRetABI(calling)
+	if err != nil {
+		return err
+	} else if internal.GetPtr(val) == nil {
+		return ctx.RegWrite(emu_x86.X86_REG_RAX, 0)
+	}
+	stream := &regStream{dbg: dbg, ctx: ctx, abi: abi, ret: true}
+	return stream.encode(val)
+}
+
+func (dbg *X86_64Dbg) Return(ctx debugger.RegisterContext) error {
+	sp, err := ctx.RegRead(emu_x86.X86_REG_RSP)
+	if err != nil {
+		return err
+	}
+	buf, err := dbg.ToPointer(sp).MemRead(POINTER_SIZE)
+	if err != nil {
+		return err
+	}
+	err = ctx.RegWrite(emu_x86.X86_REG_RSP, sp+POINTER_SIZE)
+	if err != nil {
+		return err
+	}
+	return ctx.RegWrite(emu_x86.X86_REG_RIP, binary.LittleEndian.Uint64(buf))
+}
+
+func (dbg *X86_64Dbg) InitStack() (uint64, error) {
+	region, err := dbg.MapAlloc(X86_64_STACK_SIZE, emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)
+	if err != nil {
+		return 0, err
+	}
+	stack := region.Addr + X86_64_STACK_SIZE
+	return stack, nil
+}
+
+func (dbg *X86_64Dbg) CloseStack(stack uint64) error {
+	begin := stack - X86_64_STACK_SIZE
+	return dbg.MapFree(begin, X86_64_STACK_SIZE)
+}
+
+func (dbg *X86_64Dbg) TaskControl(task debugger.Task, addr uint64) (debugger.ControlHandler, error) {
+	ctrl, err := dbg.AddControl(func(ctx debugger.Context, data any) {
+		task := data.(debugger.Task)
+		if task.Context() != ctx {
+			panic("call exception return")
+		}
+		task.CancelCause(debugger.TaskStatus_Done)
+	}, task)
+	if err != nil {
+		return nil, err
+	}
+	ctx := task.Context()
+	sp, err := ctx.RegRead(emu_x86.X86_REG_RSP)
+	if err == nil {
+		sp -= POINTER_SIZE
+		err = dbg.ToPointer(sp).MemWrite(binary.LittleEndian.AppendUint64(nil, ctrl.Addr()))
+	}
+	if err != nil {
+		ctrl.Close()
+		return nil, err
+	}
+	ctx.RegWrite(emu_x86.X86_REG_RSP, sp)
+	ctx.RegWrite(emu_x86.X86_REG_RIP, addr)
+	return ctrl, nil
+}
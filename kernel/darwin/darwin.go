@@ -0,0 +1,134 @@
+package darwin
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+const (
+	EXCP_SWI = 2
+
+	svcInsn   = 0xd4001001
+	carryFlag = 1 << 29
+)
+
+type darwinKernel struct {
+	kernel.Table
+	dbg  debugger.Debugger
+	hook debugger.HookHandler
+	args [6]emulator.Reg
+	mu   sync.Mutex
+	port uint64
+}
+
+func New(dbg debugger.Debugger) (kernel.Kernel, error) {
+	if dbg.Arch() != emulator.ARCH_ARM64 {
+		return nil, emulator.ErrArchUnsupported
+	}
+	k := &darwinKernel{
+		Table: kernel.NewTable(),
+		dbg:   dbg,
+		args:  [6]emulator.Reg{emu_arm64.ARM64_REG_X0, emu_arm64.ARM64_REG_X1, emu_arm64.ARM64_REG_X2, emu_arm64.ARM64_REG_X3, emu_arm64.ARM64_REG_X4, emu_arm64.ARM64_REG_X5},
+		port:  replyPort,
+	}
+	k.registerSyscalls()
+	k.registerTraps()
+	hook, err := dbg.AddHook(emulator.HOOK_TYPE_INTR, k.handleInterrupt, nil, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	k.hook = hook
+	return k, nil
+}
+
+func (k *darwinKernel) Close() error {
+	if k.hook != nil {
+		k.hook.Close()
+		k.hook = nil
+	}
+	return nil
+}
+
+func (k *darwinKernel) Debugger() debugger.Debugger {
+	return k.dbg
+}
+
+func (k *darwinKernel) Decode(ctx debugger.Context, intno uint64) (int64, kernel.Args, bool) {
+	var args kernel.Args
+	if intno != EXCP_SWI || !k.isSyscall(ctx) {
+		return 0, args, false
+	}
+	nr, err := ctx.RegRead(emu_arm64.ARM64_REG_X16)
+	if err != nil {
+		return 0, args, false
+	}
+	for i, reg := range k.args {
+		args[i], err = ctx.RegRead(reg)
+		if err != nil {
+			return 0, args, false
+		}
+	}
+	return int64(nr), args, true
+}
+
+func (k *darwinKernel) Result(ctx debugger.Context) (uint64, error) {
+	ret, err := ctx.RegRead(k.args[0])
+	if err != nil {
+		return 0, err
+	}
+	nr, err := ctx.RegRead(emu_arm64.ARM64_REG_X16)
+	if err != nil || int64(nr) < 0 {
+		return ret, err
+	}
+	nzcv, err := ctx.RegRead(emu_arm64.ARM64_REG_NZCV)
+	if err != nil {
+		return 0, err
+	}
+	if nzcv&carryFlag != 0 {
+		return ret, fromErrno(ret)
+	}
+	return ret, nil
+}
+
+func (k *darwinKernel) handleInterrupt(ctx debugger.Context, intno uint64, data any) debugger.HookResult {
+	nr, args, ok := k.Decode(ctx, intno)
+	if !ok {
+		return debugger.HookResult_Next
+	}
+	ret, err := k.Call(ctx, nr, args)
+	if err != nil && kernel.Exit(ctx, err) {
+		return debugger.HookResult_Done
+	}
+	if nr < 0 {
+		if err != nil {
+			ret = KERN_FAILURE
+		}
+		ctx.RegWrite(k.args[0], ret)
+		return debugger.HookResult_Done
+	}
+	nzcv, _ := ctx.RegRead(emu_arm64.ARM64_REG_NZCV)
+	if err != nil {
+		ret = toErrno(kernel.ToErrno(err))
+		nzcv |= carryFlag
+	} else {
+		nzcv &^= carryFlag
+	}
+	ctx.RegWrite(emu_arm64.ARM64_REG_NZCV, nzcv)
+	ctx.RegWrite(k.args[0], ret)
+	return debugger.HookResult_Done
+}
+
+func (k *darwinKernel) isSyscall(ctx debugger.Context) bool {
+	pc, err := ctx.RegRead(ctx.PC())
+	if err != nil {
+		return false
+	}
+	var insn uint32
+	err = ctx.ToPointer(pc-4).MemReadPtr(4, unsafe.Pointer(&insn))
+	return err == nil && insn == svcInsn
+}
@@ -0,0 +1,64 @@
+package darwin
+
+import "github.com/wnxd/microdbg/kernel"
+
+var xnuErrno = map[kernel.Errno]uint64{
+	kernel.EAGAIN:          35,
+	kernel.EDEADLK:         11,
+	kernel.ENAMETOOLONG:    63,
+	kernel.ENOLCK:          77,
+	kernel.ENOSYS:          78,
+	kernel.ENOTEMPTY:       66,
+	kernel.ELOOP:           62,
+	kernel.EOVERFLOW:       84,
+	kernel.EILSEQ:          92,
+	kernel.ENOTSOCK:        38,
+	kernel.EDESTADDRREQ:    39,
+	kernel.EMSGSIZE:        40,
+	kernel.EPROTOTYPE:      41,
+	kernel.ENOPROTOOPT:     42,
+	kernel.EPROTONOSUPPORT: 43,
+	kernel.EOPNOTSUPP:      45,
+	kernel.EAFNOSUPPORT:    47,
+	kernel.EADDRINUSE:      48,
+	kernel.EADDRNOTAVAIL:   49,
+	kernel.ENETDOWN:        50,
+	kernel.ENETUNREACH:     51,
+	kernel.ECONNABORTED:    53,
+	kernel.ECONNRESET:      54,
+	kernel.ENOBUFS:         55,
+	kernel.EISCONN:         56,
+	kernel.ENOTCONN:        57,
+	kernel.ETIMEDOUT:       60,
+	kernel.ECONNREFUSED:    61,
+	kernel.EHOSTUNREACH:    65,
+	kernel.EALREADY:        37,
+	kernel.EINPROGRESS:     36,
+	kernel.ECANCELED:       89,
+}
+
+var linuxErrno = func() map[uint64]kernel.Errno {
+	m := make(map[uint64]kernel.Errno, len(xnuErrno))
+	for k, v := range xnuErrno {
+		m[v] = k
+	}
+	return m
+}()
+
+func toErrno(errno kernel.Errno) uint64 {
+	if v, ok := xnuErrno[errno]; ok {
+		return v
+	} else if errno > kernel.ERANGE {
+		return uint64(kernel.EIO)
+	}
+	return uint64(errno)
+}
+
+func fromErrno(errno uint64) kernel.Errno {
+	if v, ok := linuxErrno[errno]; ok {
+		return v
+	} else if errno > uint64(kernel.ERANGE) {
+		return kernel.EIO
+	}
+	return kernel.Errno(errno)
+}
@@ -0,0 +1,319 @@
+package darwin
+
+import (
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+const (
+	AT_FDCWD = -2
+
+	O_ACCMODE = 0x3
+	O_APPEND  = 0x8
+	O_SYNC    = 0x80
+	O_CREAT   = 0x200
+	O_TRUNC   = 0x400
+	O_EXCL    = 0x800
+
+	PROT_READ  = 0x1
+	PROT_WRITE = 0x2
+	PROT_EXEC  = 0x4
+
+	MAP_FIXED = 0x10
+	MAP_ANON  = 0x1000
+
+	KERN_SUCCESS = 0
+	KERN_FAILURE = 5
+
+	taskPort   = 0x103
+	hostPort   = 0x203
+	threadPort = 0x303
+	replyPort  = 0x1003
+
+	ioLimit = 0x100000
+)
+
+func (k *darwinKernel) exit(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return 0, &kernel.ExitError{Code: int(int32(args[0])), Group: true}
+}
+
+func (k *darwinKernel) getpid(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return uint64(ctx.TaskID()), nil
+}
+
+func (k *darwinKernel) getuid(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return 501, nil
+}
+
+func (k *darwinKernel) issetugid(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return 0, nil
+}
+
+func (k *darwinKernel) open(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.openFile(ctx, AT_FDCWD, args[0], args[1], args[2])
+}
+
+func (k *darwinKernel) openat(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.openFile(ctx, int64(int32(args[0])), args[1], args[2], args[3])
+}
+
+func (k *darwinKernel) openFile(ctx debugger.Context, dirfd int64, pathname, flags, mode uint64) (uint64, error) {
+	name, err := ctx.ToPointer(pathname).MemReadString()
+	if err != nil {
+		return 0, kernel.EFAULT
+	}
+	if !path.IsAbs(name) && dirfd != AT_FDCWD {
+		dir, err := k.dbg.GetFile(int(dirfd))
+		if err != nil {
+			return 0, kernel.EBADF
+		}
+		d, ok := dir.(filesystem.Dir)
+		if !ok {
+			return 0, kernel.ENOTDIR
+		}
+		file, err := d.OpenFile(name, toFileFlag(flags), fs.FileMode(mode&0o7777))
+		if err != nil {
+			return 0, err
+		}
+		return uint64(k.dbg.CreateFileDescriptor(file)), nil
+	}
+	file, err := k.dbg.OpenFile(name, toFileFlag(flags), fs.FileMode(mode&0o7777))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(k.dbg.CreateFileDescriptor(file)), nil
+}
+
+func (k *darwinKernel) close(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.CloseFileDescriptor(int(int32(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	return 0, file.Close()
+}
+
+func (k *darwinKernel) read(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.GetFile(int(int32(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	r, ok := file.(filesystem.ReadFile)
+	if !ok {
+		return 0, kernel.EBADF
+	}
+	buf := make([]byte, min(args[2], ioLimit))
+	n, err := r.Read(buf)
+	if err != nil && err != io.EOF && n == 0 {
+		return 0, err
+	}
+	if err = ctx.ToPointer(args[1]).MemWrite(buf[:n]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return uint64(n), nil
+}
+
+func (k *darwinKernel) write(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.GetFile(int(int32(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	w, ok := file.(filesystem.WriteFile)
+	if !ok {
+		return 0, kernel.EBADF
+	}
+	buf, err := ctx.ToPointer(args[1]).MemRead(min(args[2], ioLimit))
+	if err != nil {
+		return 0, kernel.EFAULT
+	}
+	n, err := w.Write(buf)
+	if err != nil && n == 0 {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+func (k *darwinKernel) lseek(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	off, err := kernel.Seek(k.dbg, int(int32(args[0])), int64(args[1]), int(args[2]))
+	return uint64(off), err
+}
+
+func (k *darwinKernel) fstat(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.GetFile(int(int32(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	var buf [144]byte
+	binary.LittleEndian.PutUint16(buf[4:], uint16(kernel.Mode(info.Mode())))
+	binary.LittleEndian.PutUint16(buf[6:], 1)
+	mtime := info.ModTime()
+	for _, off := range []int{32, 48, 64, 80} {
+		binary.LittleEndian.PutUint64(buf[off:], uint64(mtime.Unix()))
+		binary.LittleEndian.PutUint64(buf[off+8:], uint64(mtime.Nanosecond()))
+	}
+	binary.LittleEndian.PutUint64(buf[96:], uint64(info.Size()))
+	binary.LittleEndian.PutUint64(buf[104:], uint64(info.Size()+511)/512)
+	binary.LittleEndian.PutUint32(buf[112:], 0x4000)
+	if err = ctx.ToPointer(args[1]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func (k *darwinKernel) mmap(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	addr, length, prot, flags, offset := args[0], args[1], args[2], args[3], args[5]
+	pageSize := k.dbg.Emulator().PageSize()
+	if length == 0 || offset&(pageSize-1) != 0 {
+		return 0, kernel.EINVAL
+	}
+	size := debugger.Align(length, pageSize)
+	var file filesystem.File
+	if flags&MAP_ANON == 0 {
+		var err error
+		file, err = k.dbg.GetFile(int(int32(args[4])))
+		if err != nil {
+			return 0, kernel.EBADF
+		}
+	}
+	var region emulator.MemRegion
+	var err error
+	if flags&MAP_FIXED != 0 {
+		if addr&(pageSize-1) != 0 {
+			return 0, kernel.EINVAL
+		}
+		k.dbg.MemUnmap(addr, size)
+		region, err = k.dbg.MemMap(addr, size, toProt(prot))
+	} else {
+		region, err = k.dbg.MapAlloc(size, toProt(prot))
+	}
+	if err != nil {
+		return 0, kernel.ENOMEM
+	}
+	if file != nil {
+		err = kernel.ReadAt(k.dbg, file, region.Addr, length, int64(offset))
+		if err != nil {
+			k.dbg.MapFree(region.Addr, region.Size)
+			return 0, err
+		}
+	}
+	return region.Addr, nil
+}
+
+func (k *darwinKernel) munmap(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	pageSize := k.dbg.Emulator().PageSize()
+	addr, size := args[0], debugger.Align(args[1], pageSize)
+	if addr&(pageSize-1) != 0 || size == 0 {
+		return 0, kernel.EINVAL
+	}
+	if k.dbg.MapFree(addr, size) != nil {
+		k.dbg.MemUnmap(addr, size)
+	}
+	return 0, nil
+}
+
+func (k *darwinKernel) mprotect(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	size := debugger.Align(args[1], k.dbg.Emulator().PageSize())
+	if k.dbg.MemProtect(args[0], size, toProt(args[2])) != nil {
+		return 0, kernel.ENOMEM
+	}
+	return 0, nil
+}
+
+func (k *darwinKernel) gettimeofday(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	if args[0] == 0 {
+		return 0, nil
+	}
+	now := k.dbg.Clock().Now()
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(now.Unix()))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(now.Nanosecond()/1000))
+	if err := ctx.ToPointer(args[0]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func (k *darwinKernel) machAbsoluteTime(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return uint64(k.dbg.Clock().Monotonic()), nil
+}
+
+func (k *darwinKernel) machTimebaseInfo(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint32(buf[:], 1)
+	binary.LittleEndian.PutUint32(buf[4:], 1)
+	if err := ctx.ToPointer(args[0]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return KERN_SUCCESS, nil
+}
+
+func (k *darwinKernel) taskSelf(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return taskPort, nil
+}
+
+func (k *darwinKernel) hostSelf(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return hostPort, nil
+}
+
+func (k *darwinKernel) threadSelf(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return threadPort + uint64(ctx.TaskID())<<8, nil
+}
+
+func (k *darwinKernel) machReplyPort(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.port += 0x100
+	return k.port, nil
+}
+
+func toFileFlag(flags uint64) filesystem.FileFlag {
+	var flag filesystem.FileFlag
+	switch flags & O_ACCMODE {
+	case 1:
+		flag = filesystem.O_WRONLY
+	case 2:
+		flag = filesystem.O_RDWR
+	default:
+		flag = filesystem.O_RDONLY
+	}
+	if flags&O_CREAT != 0 {
+		flag |= filesystem.O_CREATE
+	}
+	if flags&O_EXCL != 0 {
+		flag |= filesystem.O_EXCL
+	}
+	if flags&O_TRUNC != 0 {
+		flag |= filesystem.O_TRUNC
+	}
+	if flags&O_APPEND != 0 {
+		flag |= filesystem.O_APPEND
+	}
+	if flags&O_SYNC != 0 {
+		flag |= filesystem.O_SYNC
+	}
+	return flag
+}
+
+func toProt(prot uint64) emulator.MemProt {
+	var p emulator.MemProt
+	if prot&PROT_READ != 0 {
+		p |= emulator.MEM_PROT_READ
+	}
+	if prot&PROT_WRITE != 0 {
+		p |= emulator.MEM_PROT_WRITE
+	}
+	if prot&PROT_EXEC != 0 {
+		p |= emulator.MEM_PROT_EXEC
+	}
+	return p
+}
@@ -0,0 +1,32 @@
+package darwin
+
+func (k *darwinKernel) registerSyscalls() {
+	k.Register(1, "exit", k.exit)
+	k.Register(3, "read", k.read)
+	k.Register(4, "write", k.write)
+	k.Register(5, "open", k.open)
+	k.Register(6, "close", k.close)
+	k.Register(20, "getpid", k.getpid)
+	k.Register(24, "getuid", k.getuid)
+	k.Register(25, "geteuid", k.getuid)
+	k.Register(43, "getegid", k.getuid)
+	k.Register(47, "getgid", k.getuid)
+	k.Register(73, "munmap", k.munmap)
+	k.Register(74, "mprotect", k.mprotect)
+	k.Register(116, "gettimeofday", k.gettimeofday)
+	k.Register(189, "fstat", k.fstat)
+	k.Register(197, "mmap", k.mmap)
+	k.Register(199, "lseek", k.lseek)
+	k.Register(327, "issetugid", k.issetugid)
+	k.Register(339, "fstat64", k.fstat)
+	k.Register(463, "openat", k.openat)
+}
+
+func (k *darwinKernel) registerTraps() {
+	k.Register(-3, "mach_absolute_time", k.machAbsoluteTime)
+	k.Register(-26, "mach_reply_port", k.machReplyPort)
+	k.Register(-27, "thread_self", k.threadSelf)
+	k.Register(-28, "mach_task_self", k.taskSelf)
+	k.Register(-29, "mach_host_self", k.hostSelf)
+	k.Register(-89, "mach_timebase_info", k.machTimebaseInfo)
+}
@@ -0,0 +1,53 @@
+package kernel
+
+import (
+	"errors"
+	"io"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+func Seek(dbg debugger.Debugger, fd int, offset int64, whence int) (int64, error) {
+	file, err := dbg.GetFile(fd)
+	if err != nil {
+		return 0, EBADF
+	}
+	s, ok := file.(filesystem.SeekFile)
+	if !ok {
+		return 0, ESPIPE
+	}
+	off, err := s.Seek(offset, whence)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return 0, ESPIPE
+	}
+	return off, err
+}
+
+func ReadAt(dbg debugger.Debugger, file filesystem.File, addr, size uint64, offset int64) error {
+	buf := make([]byte, size)
+	var n int
+	var err error
+	if r, ok := file.(io.ReaderAt); ok {
+		n, err = r.ReadAt(buf, offset)
+	} else if s, ok := file.(filesystem.SeekFile); ok {
+		r, ok := file.(filesystem.ReadFile)
+		if !ok {
+			return EACCES
+		}
+		var cur int64
+		if cur, err = s.Seek(0, io.SeekCurrent); err != nil {
+			return ENODEV
+		}
+		if _, err = s.Seek(offset, io.SeekStart); err == nil {
+			n, err = io.ReadFull(r, buf)
+		}
+		s.Seek(cur, io.SeekStart)
+	} else {
+		return ENODEV
+	}
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	return dbg.Emulator().MemWrite(addr, buf[:n])
+}
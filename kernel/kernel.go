@@ -0,0 +1,165 @@
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+type Args [6]uint64
+
+type Handler = func(ctx debugger.Context, args Args) (uint64, error)
+
+type Interceptor = func(ctx debugger.Context, nr int64, sc Syscall, args Args) (uint64, error)
+
+type Syscall struct {
+	Name    string
+	Handler Handler
+}
+
+type Table interface {
+	Register(nr int64, name string, handler Handler)
+	Unregister(nr int64)
+	Lookup(nr int64) (Syscall, bool)
+	Number(name string) (int64, bool)
+	Numbers() []int64
+	Intercept(interceptor Interceptor)
+	Call(ctx debugger.Context, nr int64, args Args) (uint64, error)
+}
+
+type Kernel interface {
+	io.Closer
+	Table
+	Debugger() debugger.Debugger
+	Decode(ctx debugger.Context, intno uint64) (int64, Args, bool)
+	Result(ctx debugger.Context) (uint64, error)
+}
+
+type ExitError struct {
+	Code  int
+	Group bool
+}
+
+type table struct {
+	mu          sync.RWMutex
+	syscalls    map[int64]Syscall
+	interceptor Interceptor
+}
+
+func NewTable() Table {
+	return &table{syscalls: make(map[int64]Syscall)}
+}
+
+func (t *table) Register(nr int64, name string, handler Handler) {
+	t.mu.Lock()
+	t.syscalls[nr] = Syscall{Name: name, Handler: handler}
+	t.mu.Unlock()
+}
+
+func (t *table) Unregister(nr int64) {
+	t.mu.Lock()
+	delete(t.syscalls, nr)
+	t.mu.Unlock()
+}
+
+func (t *table) Lookup(nr int64) (Syscall, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	sc, ok := t.syscalls[nr]
+	return sc, ok
+}
+
+func (t *table) Number(name string) (int64, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for nr, sc := range t.syscalls {
+		if sc.Name == name {
+			return nr, true
+		}
+	}
+	return 0, false
+}
+
+func (t *table) Numbers() []int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Sorted(maps.Keys(t.syscalls))
+}
+
+func (t *table) Intercept(interceptor Interceptor) {
+	t.mu.Lock()
+	t.interceptor = interceptor
+	t.mu.Unlock()
+}
+
+func (t *table) Call(ctx debugger.Context, nr int64, args Args) (uint64, error) {
+	t.mu.RLock()
+	sc, ok := t.syscalls[nr]
+	interceptor := t.interceptor
+	t.mu.RUnlock()
+	if !ok {
+		sc.Name = fmt.Sprintf("syscall_%d", nr)
+	}
+	if sc.Handler == nil {
+		sc.Handler = enosys
+	}
+	if interceptor != nil {
+		return interceptor(ctx, nr, sc, args)
+	}
+	return sc.Handler(ctx, args)
+}
+
+func enosys(ctx debugger.Context, args Args) (uint64, error) {
+	return 0, ENOSYS
+}
+
+func Exit(ctx debugger.Context, err error) bool {
+	var exit *ExitError
+	if !errors.As(err, &exit) {
+		return false
+	}
+	if task, ok := ctx.(debugger.Task); ok {
+		task.CancelCause(exit)
+	}
+	return true
+}
+
+func Mode(mode fs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	switch mode.Type() {
+	case fs.ModeDir:
+		m |= 0o040000
+	case fs.ModeSymlink:
+		m |= 0o120000
+	case fs.ModeNamedPipe:
+		m |= 0o010000
+	case fs.ModeSocket:
+		m |= 0o140000
+	case fs.ModeDevice:
+		m |= 0o060000
+	case fs.ModeDevice | fs.ModeCharDevice:
+		m |= 0o020000
+	default:
+		m |= 0o100000
+	}
+	if mode&fs.ModeSetuid != 0 {
+		m |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= 0o1000
+	}
+	return m
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
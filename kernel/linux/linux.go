@@ -0,0 +1,156 @@
+package linux
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+	emu_arm64 "github.com/wnxd/microdbg/emulator/arm64"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+const EXCP_SWI = 2
+
+type linuxKernel struct {
+	kernel.Table
+	dbg     debugger.Debugger
+	hook    debugger.HookHandler
+	nr      emulator.Reg
+	args    [6]emulator.Reg
+	mu      sync.Mutex
+	heap    emulator.MemRegion
+	heapEnd uint64
+}
+
+func New(dbg debugger.Debugger) (kernel.Kernel, error) {
+	k := &linuxKernel{
+		Table: kernel.NewTable(),
+		dbg:   dbg,
+	}
+	switch dbg.Arch() {
+	case emulator.ARCH_ARM:
+		k.nr = emu_arm.ARM_REG_R7
+		k.args = [6]emulator.Reg{emu_arm.ARM_REG_R0, emu_arm.ARM_REG_R1, emu_arm.ARM_REG_R2, emu_arm.ARM_REG_R3, emu_arm.ARM_REG_R4, emu_arm.ARM_REG_R5}
+		k.registerArm()
+	case emulator.ARCH_ARM64:
+		k.nr = emu_arm64.ARM64_REG_X8
+		k.args = [6]emulator.Reg{emu_arm64.ARM64_REG_X0, emu_arm64.ARM64_REG_X1, emu_arm64.ARM64_REG_X2, emu_arm64.ARM64_REG_X3, emu_arm64.ARM64_REG_X4, emu_arm64.ARM64_REG_X5}
+		k.registerArm64()
+	default:
+		return nil, emulator.ErrArchUnsupported
+	}
+	hook, err := dbg.AddHook(emulator.HOOK_TYPE_INTR, k.handleInterrupt, nil, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	k.hook = hook
+	return k, nil
+}
+
+func (k *linuxKernel) Close() error {
+	if k.hook != nil {
+		k.hook.Close()
+		k.hook = nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.heap.Size == 0 {
+		return nil
+	}
+	err := k.dbg.MapFree(k.heap.Addr, k.heap.Size)
+	k.heap = emulator.MemRegion{}
+	return err
+}
+
+func (k *linuxKernel) Debugger() debugger.Debugger {
+	return k.dbg
+}
+
+func (k *linuxKernel) Decode(ctx debugger.Context, intno uint64) (int64, kernel.Args, bool) {
+	var args kernel.Args
+	if intno != EXCP_SWI || !k.isSyscall(ctx) {
+		return 0, args, false
+	}
+	nr, err := ctx.RegRead(k.nr)
+	if err != nil {
+		return 0, args, false
+	}
+	for i, reg := range k.args {
+		args[i], err = ctx.RegRead(reg)
+		if err != nil {
+			return 0, args, false
+		}
+	}
+	return k.int(nr), args, true
+}
+
+func (k *linuxKernel) Result(ctx debugger.Context) (uint64, error) {
+	ret, err := ctx.RegRead(k.args[0])
+	if err != nil {
+		return 0, err
+	}
+	ret = k.word(ret)
+	if v := k.int(ret); v < 0 && v >= -4095 {
+		return ret, kernel.Errno(-v)
+	}
+	return ret, nil
+}
+
+func (k *linuxKernel) handleInterrupt(ctx debugger.Context, intno uint64, data any) debugger.HookResult {
+	nr, args, ok := k.Decode(ctx, intno)
+	if !ok {
+		return debugger.HookResult_Next
+	}
+	ret, err := k.Call(ctx, nr, args)
+	if err != nil {
+		if kernel.Exit(ctx, err) {
+			return debugger.HookResult_Done
+		}
+		ret = -uint64(kernel.ToErrno(err))
+	}
+	ctx.RegWrite(k.args[0], k.word(ret))
+	return debugger.HookResult_Done
+}
+
+func (k *linuxKernel) isSyscall(ctx debugger.Context) bool {
+	pc, err := ctx.RegRead(ctx.PC())
+	if err != nil {
+		return false
+	}
+	switch k.dbg.Arch() {
+	case emulator.ARCH_ARM:
+		cpsr, err := ctx.RegRead(emu_arm.ARM_REG_CPSR)
+		if err != nil {
+			return false
+		}
+		if cpsr&0x20 != 0 {
+			var insn uint16
+			err = ctx.ToPointer(pc-2).MemReadPtr(2, unsafe.Pointer(&insn))
+			return err == nil && insn == 0xdf00
+		}
+		var insn uint32
+		err = ctx.ToPointer(pc-4).MemReadPtr(4, unsafe.Pointer(&insn))
+		return err == nil && insn&0x0fffffff == 0x0f000000
+	case emulator.ARCH_ARM64:
+		var insn uint32
+		err = ctx.ToPointer(pc-4).MemReadPtr(4, unsafe.Pointer(&insn))
+		return err == nil && insn == 0xd4000001
+	}
+	return false
+}
+
+func (k *linuxKernel) word(v uint64) uint64 {
+	if k.dbg.PointerSize() == 4 {
+		return uint64(uint32(v))
+	}
+	return v
+}
+
+func (k *linuxKernel) int(v uint64) int64 {
+	if k.dbg.PointerSize() == 4 {
+		return int64(int32(v))
+	}
+	return int64(v)
+}
@@ -0,0 +1,220 @@
+package linux
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+const (
+	AT_NULL     = 0
+	AT_PHDR     = 3
+	AT_PHENT    = 4
+	AT_PHNUM    = 5
+	AT_PAGESZ   = 6
+	AT_BASE     = 7
+	AT_FLAGS    = 8
+	AT_ENTRY    = 9
+	AT_UID      = 11
+	AT_EUID     = 12
+	AT_GID      = 13
+	AT_EGID     = 14
+	AT_PLATFORM = 15
+	AT_HWCAP    = 16
+	AT_CLKTCK   = 17
+	AT_SECURE   = 23
+	AT_RANDOM   = 25
+	AT_HWCAP2   = 26
+	AT_EXECFN   = 31
+
+	hwcapArm   = 0xb0d7
+	hwcapArm64 = 0x3
+)
+
+type Process interface {
+	io.Closer
+	Debugger() debugger.Debugger
+	Module() debugger.Module
+	Task() debugger.Task
+	Start() error
+	Wait() (int, error)
+}
+
+type ProcessConfig struct {
+	Argv   []string
+	Envp   []string
+	Random io.Reader
+}
+
+type process struct {
+	dbg    debugger.Debugger
+	module debugger.Module
+	task   debugger.Task
+}
+
+func NewProcess(ctx context.Context, dbg debugger.Debugger, module debugger.Module, config ProcessConfig) (Process, error) {
+	entry := module.EntryAddr()
+	if entry == 0 {
+		return nil, debugger.ErrArgumentInvalid
+	}
+	if len(config.Argv) == 0 {
+		config.Argv = []string{module.Name()}
+	}
+	if config.Random == nil {
+		config.Random = rand.NewChaCha8([32]byte{})
+	}
+	task, err := dbg.GetMainTask(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &process{dbg: dbg, module: module, task: task}
+	err = p.setupStack(config.Argv, config.Envp, config.Random)
+	if err == nil {
+		err = dbg.CallTaskOf(task, entry)
+	}
+	if err != nil {
+		task.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *process) Close() error {
+	return p.task.Close()
+}
+
+func (p *process) Debugger() debugger.Debugger {
+	return p.dbg
+}
+
+func (p *process) Module() debugger.Module {
+	return p.module
+}
+
+func (p *process) Task() debugger.Task {
+	return p.task
+}
+
+func (p *process) Start() error {
+	return p.task.Run()
+}
+
+func (p *process) Wait() (int, error) {
+	<-p.task.Done()
+	err := p.task.Err()
+	var exit *kernel.ExitError
+	if errors.As(err, &exit) {
+		return exit.Code, nil
+	} else if err != nil {
+		return -1, err
+	}
+	var code int32
+	err = p.task.Context().RetExtract(&code)
+	if err != nil {
+		return -1, err
+	}
+	return int(code), nil
+}
+
+func (p *process) setupStack(argv, envp []string, r io.Reader) error {
+	ctx := p.task.Context()
+	ws := p.dbg.PointerSize()
+	var platform string
+	var hwcap uint64
+	switch p.dbg.Arch() {
+	case emulator.ARCH_ARM:
+		platform, hwcap = "v7l", hwcapArm
+	case emulator.ARCH_ARM64:
+		platform, hwcap = "aarch64", hwcapArm64
+	default:
+		return emulator.ErrArchUnsupported
+	}
+	var random [16]byte
+	if _, err := io.ReadFull(r, random[:]); err != nil {
+		return err
+	}
+	strs := make([]string, 0, len(argv)+len(envp)+1)
+	strs = append(strs, argv...)
+	strs = append(strs, envp...)
+	strs = append(strs, platform)
+	var size uint64
+	for _, s := range strs {
+		size += uint64(len(s)) + 1
+	}
+	area, err := ctx.StackAlloc(size + uint64(len(random)))
+	if err != nil {
+		return err
+	}
+	addrs := make([]uint64, len(strs))
+	buf := make([]byte, 0, size+uint64(len(random)))
+	buf = append(buf, random[:]...)
+	for i, s := range strs {
+		addrs[i] = area.Address() + uint64(len(buf))
+		buf = append(buf, s...)
+		buf = append(buf, 0)
+	}
+	err = area.MemWrite(buf)
+	if err != nil {
+		return err
+	}
+	phdr, phent, phnum := p.programHeaders()
+	auxv := []uint64{
+		AT_PHDR, phdr,
+		AT_PHENT, phent,
+		AT_PHNUM, phnum,
+		AT_PAGESZ, p.dbg.Emulator().PageSize(),
+		AT_BASE, 0,
+		AT_FLAGS, 0,
+		AT_ENTRY, p.module.EntryAddr(),
+		AT_UID, 0,
+		AT_EUID, 0,
+		AT_GID, 0,
+		AT_EGID, 0,
+		AT_PLATFORM, addrs[len(addrs)-1],
+		AT_HWCAP, hwcap,
+		AT_HWCAP2, 0,
+		AT_CLKTCK, 100,
+		AT_SECURE, 0,
+		AT_RANDOM, area.Address(),
+		AT_EXECFN, addrs[0],
+		AT_NULL, 0,
+	}
+	vec := make([]uint64, 0, len(argv)+len(envp)+3+len(auxv))
+	vec = append(vec, uint64(len(argv)))
+	vec = append(vec, addrs[:len(argv)]...)
+	vec = append(vec, 0)
+	vec = append(vec, addrs[len(argv):len(argv)+len(envp)]...)
+	vec = append(vec, 0)
+	vec = append(vec, auxv...)
+	raw := make([]byte, len(vec)*int(ws))
+	for i, v := range vec {
+		if ws == 4 {
+			binary.LittleEndian.PutUint32(raw[i*4:], uint32(v))
+		} else {
+			binary.LittleEndian.PutUint64(raw[i*8:], v)
+		}
+	}
+	stack, err := ctx.StackAlloc(uint64(len(raw)))
+	if err != nil {
+		return err
+	}
+	return stack.MemWrite(raw)
+}
+
+func (p *process) programHeaders() (uint64, uint64, uint64) {
+	base := p.module.BaseAddr()
+	ehdr, err := p.dbg.Emulator().MemRead(base, 64)
+	if err != nil || string(ehdr[:4]) != "\x7fELF" {
+		return 0, 0, 0
+	}
+	if ehdr[4] == 1 {
+		return base + uint64(binary.LittleEndian.Uint32(ehdr[28:])), uint64(binary.LittleEndian.Uint16(ehdr[42:])), uint64(binary.LittleEndian.Uint16(ehdr[44:]))
+	}
+	return base + binary.LittleEndian.Uint64(ehdr[32:]), uint64(binary.LittleEndian.Uint16(ehdr[54:])), uint64(binary.LittleEndian.Uint16(ehdr[56:]))
+}
@@ -0,0 +1,365 @@
+package linux
+
+import (
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+const (
+	AT_FDCWD = -100
+
+	O_ACCMODE = 0o3
+	O_CREAT   = 0o100
+	O_EXCL    = 0o200
+	O_TRUNC   = 0o1000
+	O_APPEND  = 0o2000
+	O_SYNC    = 0o4010000
+
+	PROT_READ  = 0x1
+	PROT_WRITE = 0x2
+	PROT_EXEC  = 0x4
+
+	MAP_FIXED     = 0x10
+	MAP_ANONYMOUS = 0x20
+
+	CLOCK_REALTIME        = 0
+	CLOCK_REALTIME_COARSE = 5
+	CLOCK_BOOTTIME        = 7
+
+	heapSize = 0x800000
+	ioLimit  = 0x100000
+)
+
+func (k *linuxKernel) exit(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return 0, &kernel.ExitError{Code: int(int32(args[0]))}
+}
+
+func (k *linuxKernel) exitGroup(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return 0, &kernel.ExitError{Code: int(int32(args[0])), Group: true}
+}
+
+func (k *linuxKernel) getpid(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return uint64(ctx.TaskID()), nil
+}
+
+func (k *linuxKernel) open(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.openFile(ctx, AT_FDCWD, args[0], args[1], args[2])
+}
+
+func (k *linuxKernel) openat(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.openFile(ctx, k.int(args[0]), args[1], args[2], args[3])
+}
+
+func (k *linuxKernel) openFile(ctx debugger.Context, dirfd int64, pathname, flags, mode uint64) (uint64, error) {
+	name, err := ctx.ToPointer(pathname).MemReadString()
+	if err != nil {
+		return 0, kernel.EFAULT
+	}
+	if !path.IsAbs(name) && dirfd != AT_FDCWD {
+		dir, err := k.dbg.GetFile(int(dirfd))
+		if err != nil {
+			return 0, kernel.EBADF
+		}
+		info, err := dir.Stat()
+		if err != nil || !info.IsDir() {
+			return 0, kernel.ENOTDIR
+		}
+		if d, ok := dir.(filesystem.Dir); ok {
+			file, err := d.OpenFile(name, toFileFlag(flags), fs.FileMode(mode&0o7777))
+			if err != nil {
+				return 0, err
+			}
+			return uint64(k.dbg.CreateFileDescriptor(file)), nil
+		}
+		return 0, kernel.ENOTDIR
+	}
+	file, err := k.dbg.OpenFile(name, toFileFlag(flags), fs.FileMode(mode&0o7777))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(k.dbg.CreateFileDescriptor(file)), nil
+}
+
+func (k *linuxKernel) close(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.CloseFileDescriptor(int(k.int(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	return 0, file.Close()
+}
+
+func (k *linuxKernel) read(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.GetFile(int(k.int(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	r, ok := file.(filesystem.ReadFile)
+	if !ok {
+		return 0, kernel.EBADF
+	}
+	buf := make([]byte, min(k.word(args[2]), ioLimit))
+	n, err := r.Read(buf)
+	if err != nil && err != io.EOF && n == 0 {
+		return 0, err
+	}
+	if err = ctx.ToPointer(args[1]).MemWrite(buf[:n]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return uint64(n), nil
+}
+
+func (k *linuxKernel) write(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	file, err := k.dbg.GetFile(int(k.int(args[0])))
+	if err != nil {
+		return 0, kernel.EBADF
+	}
+	w, ok := file.(filesystem.WriteFile)
+	if !ok {
+		return 0, kernel.EBADF
+	}
+	buf, err := ctx.ToPointer(args[1]).MemRead(min(k.word(args[2]), ioLimit))
+	if err != nil {
+		return 0, kernel.EFAULT
+	}
+	n, err := w.Write(buf)
+	if err != nil && n == 0 {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+func (k *linuxKernel) lseek(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	off, err := kernel.Seek(k.dbg, int(k.int(args[0])), k.int(args[1]), int(args[2]))
+	return uint64(off), err
+}
+
+func (k *linuxKernel) llseek(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	off, err := kernel.Seek(k.dbg, int(k.int(args[0])), int64(args[1]<<32|uint64(uint32(args[2]))), int(args[4]))
+	if err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(off))
+	if err = ctx.ToPointer(args[3]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func (k *linuxKernel) fstat(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	info, err := k.stat(int(k.int(args[0])))
+	if err != nil {
+		return 0, err
+	}
+	var buf [128]byte
+	binary.LittleEndian.PutUint32(buf[16:], kernel.Mode(info.Mode()))
+	binary.LittleEndian.PutUint32(buf[20:], 1)
+	binary.LittleEndian.PutUint64(buf[48:], uint64(info.Size()))
+	binary.LittleEndian.PutUint32(buf[56:], 0x1000)
+	binary.LittleEndian.PutUint64(buf[64:], uint64(info.Size()+511)/512)
+	mtime := info.ModTime()
+	for _, off := range []int{72, 88, 104} {
+		binary.LittleEndian.PutUint64(buf[off:], uint64(mtime.Unix()))
+		binary.LittleEndian.PutUint64(buf[off+8:], uint64(mtime.Nanosecond()))
+	}
+	if err = ctx.ToPointer(args[1]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func (k *linuxKernel) fstat64(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	info, err := k.stat(int(k.int(args[0])))
+	if err != nil {
+		return 0, err
+	}
+	var buf [104]byte
+	binary.LittleEndian.PutUint32(buf[16:], kernel.Mode(info.Mode()))
+	binary.LittleEndian.PutUint32(buf[20:], 1)
+	binary.LittleEndian.PutUint64(buf[48:], uint64(info.Size()))
+	binary.LittleEndian.PutUint32(buf[56:], 0x1000)
+	binary.LittleEndian.PutUint64(buf[64:], uint64(info.Size()+511)/512)
+	mtime := info.ModTime()
+	for _, off := range []int{72, 80, 88} {
+		binary.LittleEndian.PutUint32(buf[off:], uint32(mtime.Unix()))
+		binary.LittleEndian.PutUint32(buf[off+4:], uint32(mtime.Nanosecond()))
+	}
+	if err = ctx.ToPointer(args[1]).MemWrite(buf[:]); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func (k *linuxKernel) stat(fd int) (fs.FileInfo, error) {
+	file, err := k.dbg.GetFile(fd)
+	if err != nil {
+		return nil, kernel.EBADF
+	}
+	return file.Stat()
+}
+
+func (k *linuxKernel) brk(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.heap.Size == 0 {
+		heap, err := k.dbg.MapAlloc(heapSize, emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)
+		if err != nil {
+			return 0, nil
+		}
+		k.heap, k.heapEnd = heap, heap.Addr
+	}
+	if addr := k.word(args[0]); addr >= k.heap.Addr && addr <= k.heap.Addr+k.heap.Size {
+		k.heapEnd = addr
+	}
+	return k.heapEnd, nil
+}
+
+func (k *linuxKernel) mmap(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.mapMemory(args[0], k.word(args[1]), args[2], args[3], int(k.int(args[4])), args[5])
+}
+
+func (k *linuxKernel) mmap2(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.mapMemory(args[0], k.word(args[1]), args[2], args[3], int(k.int(args[4])), k.word(args[5])*0x1000)
+}
+
+func (k *linuxKernel) mapMemory(addr, length, prot, flags uint64, fd int, offset uint64) (uint64, error) {
+	pageSize := k.dbg.Emulator().PageSize()
+	if length == 0 || offset&(pageSize-1) != 0 {
+		return 0, kernel.EINVAL
+	}
+	size := debugger.Align(length, pageSize)
+	var file filesystem.File
+	if flags&MAP_ANONYMOUS == 0 {
+		var err error
+		file, err = k.dbg.GetFile(fd)
+		if err != nil {
+			return 0, kernel.EBADF
+		}
+	}
+	var region emulator.MemRegion
+	var err error
+	if flags&MAP_FIXED != 0 {
+		if addr&(pageSize-1) != 0 {
+			return 0, kernel.EINVAL
+		}
+		k.dbg.MemUnmap(addr, size)
+		region, err = k.dbg.MemMap(addr, size, toProt(prot))
+	} else {
+		region, err = k.dbg.MapAlloc(size, toProt(prot))
+	}
+	if err != nil {
+		return 0, kernel.ENOMEM
+	}
+	if file != nil {
+		err = kernel.ReadAt(k.dbg, file, region.Addr, length, int64(offset))
+		if err != nil {
+			k.dbg.MapFree(region.Addr, region.Size)
+			return 0, err
+		}
+	}
+	return region.Addr, nil
+}
+
+func (k *linuxKernel) munmap(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	addr, size := args[0], debugger.Align(k.word(args[1]), k.dbg.Emulator().PageSize())
+	if addr&(k.dbg.Emulator().PageSize()-1) != 0 || size == 0 {
+		return 0, kernel.EINVAL
+	}
+	if k.dbg.MapFree(addr, size) != nil {
+		k.dbg.MemUnmap(addr, size)
+	}
+	return 0, nil
+}
+
+func (k *linuxKernel) mprotect(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	size := debugger.Align(k.word(args[1]), k.dbg.Emulator().PageSize())
+	if k.dbg.MemProtect(args[0], size, toProt(args[2])) != nil {
+		return 0, kernel.ENOMEM
+	}
+	return 0, nil
+}
+
+func (k *linuxKernel) clockGettime(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.clock(ctx, k.int(args[0]), args[1], k.dbg.PointerSize())
+}
+
+func (k *linuxKernel) clockGettime64(ctx debugger.Context, args kernel.Args) (uint64, error) {
+	return k.clock(ctx, k.int(args[0]), args[1], 8)
+}
+
+func (k *linuxKernel) clock(ctx debugger.Context, id int64, tp uint64, size uint64) (uint64, error) {
+	var sec, nsec int64
+	switch id {
+	case CLOCK_REALTIME, CLOCK_REALTIME_COARSE:
+		now := k.dbg.Clock().Now()
+		sec, nsec = now.Unix(), int64(now.Nanosecond())
+	default:
+		if id < 0 || id > CLOCK_BOOTTIME {
+			return 0, kernel.EINVAL
+		}
+		d := k.dbg.Clock().Monotonic()
+		sec, nsec = int64(d/time.Second), int64(d%time.Second)
+	}
+	buf := make([]byte, size*2)
+	if size == 4 {
+		binary.LittleEndian.PutUint32(buf, uint32(sec))
+		binary.LittleEndian.PutUint32(buf[4:], uint32(nsec))
+	} else {
+		binary.LittleEndian.PutUint64(buf, uint64(sec))
+		binary.LittleEndian.PutUint64(buf[8:], uint64(nsec))
+	}
+	if err := ctx.ToPointer(tp).MemWrite(buf); err != nil {
+		return 0, kernel.EFAULT
+	}
+	return 0, nil
+}
+
+func toFileFlag(flags uint64) filesystem.FileFlag {
+	var flag filesystem.FileFlag
+	switch flags & O_ACCMODE {
+	case 1:
+		flag = filesystem.O_WRONLY
+	case 2:
+		flag = filesystem.O_RDWR
+	default:
+		flag = filesystem.O_RDONLY
+	}
+	if flags&O_CREAT != 0 {
+		flag |= filesystem.O_CREATE
+	}
+	if flags&O_EXCL != 0 {
+		flag |= filesystem.O_EXCL
+	}
+	if flags&O_TRUNC != 0 {
+		flag |= filesystem.O_TRUNC
+	}
+	if flags&O_APPEND != 0 {
+		flag |= filesystem.O_APPEND
+	}
+	if flags&O_SYNC == O_SYNC {
+		flag |= filesystem.O_SYNC
+	}
+	return flag
+}
+
+func toProt(prot uint64) emulator.MemProt {
+	var p emulator.MemProt
+	if prot&PROT_READ != 0 {
+		p |= emulator.MEM_PROT_READ
+	}
+	if prot&PROT_WRITE != 0 {
+		p |= emulator.MEM_PROT_WRITE
+	}
+	if prot&PROT_EXEC != 0 {
+		p |= emulator.MEM_PROT_EXEC
+	}
+	return p
+}
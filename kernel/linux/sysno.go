@@ -0,0 +1,38 @@
+package linux
+
+func (k *linuxKernel) registerArm() {
+	k.Register(1, "exit", k.exit)
+	k.Register(3, "read", k.read)
+	k.Register(4, "write", k.write)
+	k.Register(5, "open", k.open)
+	k.Register(6, "close", k.close)
+	k.Register(19, "lseek", k.lseek)
+	k.Register(20, "getpid", k.getpid)
+	k.Register(45, "brk", k.brk)
+	k.Register(91, "munmap", k.munmap)
+	k.Register(125, "mprotect", k.mprotect)
+	k.Register(140, "_llseek", k.llseek)
+	k.Register(192, "mmap2", k.mmap2)
+	k.Register(197, "fstat64", k.fstat64)
+	k.Register(248, "exit_group", k.exitGroup)
+	k.Register(263, "clock_gettime", k.clockGettime)
+	k.Register(322, "openat", k.openat)
+	k.Register(403, "clock_gettime64", k.clockGettime64)
+}
+
+func (k *linuxKernel) registerArm64() {
+	k.Register(56, "openat", k.openat)
+	k.Register(57, "close", k.close)
+	k.Register(62, "lseek", k.lseek)
+	k.Register(63, "read", k.read)
+	k.Register(64, "write", k.write)
+	k.Register(80, "fstat", k.fstat)
+	k.Register(93, "exit", k.exit)
+	k.Register(94, "exit_group", k.exitGroup)
+	k.Register(113, "clock_gettime", k.clockGettime)
+	k.Register(172, "getpid", k.getpid)
+	k.Register(214, "brk", k.brk)
+	k.Register(215, "munmap", k.munmap)
+	k.Register(222, "mmap", k.mmap)
+	k.Register(226, "mprotect", k.mprotect)
+}
@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+type Mode int
+
+const (
+	Mode_Enforce Mode = iota
+	Mode_Record
+)
+
+type Config struct {
+	Mode    Mode         `json:"-"`
+	Default Action       `json:"default"`
+	Errno   kernel.Errno `json:"errno,omitempty"`
+	Rules   []Rule       `json:"rules"`
+}
+
+type Decision struct {
+	Kind    Kind
+	Op      string
+	Subject string
+	Action  Action
+	Errno   kernel.Errno
+	Rule    int
+}
+
+type Policy interface {
+	io.Closer
+	Decide(kind Kind, subject string) Decision
+	Recorded() Config
+}
+
+type recordKey struct {
+	kind    Kind
+	subject string
+}
+
+type policy struct {
+	mu       sync.Mutex
+	dbg      debugger.Debugger
+	kernel   kernel.Kernel
+	w        io.Writer
+	config   Config
+	recorded []recordKey
+	seen     map[recordKey]struct{}
+}
+
+func New(dbg debugger.Debugger, k kernel.Kernel, w io.Writer, config Config) (Policy, error) {
+	if dbg == nil {
+		return nil, debugger.ErrArgumentInvalid
+	}
+	if config.Errno == 0 {
+		config.Errno = kernel.EPERM
+	}
+	p := &policy{
+		dbg:    dbg,
+		kernel: k,
+		w:      w,
+		config: config,
+		seen:   make(map[recordKey]struct{}),
+	}
+	if k != nil {
+		k.Intercept(p.intercept)
+	}
+	dbg.SetFileFilter(p.filter)
+	return p, nil
+}
+
+func (p *policy) Close() error {
+	p.dbg.SetFileFilter(nil)
+	if p.kernel != nil {
+		p.kernel.Intercept(nil)
+	}
+	return nil
+}
+
+func (p *policy) Decide(kind Kind, subject string) Decision {
+	d := Decision{Kind: kind, Subject: subject, Action: p.config.Default, Rule: -1}
+	if p.config.Mode == Mode_Record {
+		p.record(kind, subject)
+		d.Action = Action_Allow
+		return d
+	}
+	for i := range p.config.Rules {
+		if rule := &p.config.Rules[i]; rule.Match(kind, subject) {
+			d.Action, d.Errno, d.Rule = rule.Action, rule.Errno, i
+			break
+		}
+	}
+	if d.Action == Action_Deny && d.Errno == 0 {
+		d.Errno = p.config.Errno
+	}
+	return d
+}
+
+func (p *policy) Recorded() Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	config := Config{Default: Action_Deny, Errno: p.config.Errno}
+	for _, key := range p.recorded {
+		config.Rules = append(config.Rules, Rule{Kind: key.kind, Pattern: patternEscaper.Replace(key.subject), Action: Action_Allow})
+	}
+	return config
+}
+
+func (p *policy) intercept(ctx debugger.Context, nr int64, sc kernel.Syscall, args kernel.Args) (uint64, error) {
+	d := p.Decide(Kind_Syscall, sc.Name)
+	p.log(d)
+	switch d.Action {
+	case Action_Deny:
+		return 0, d.Errno
+	case Action_Emulate:
+		if handler := p.config.Rules[d.Rule].Syscall; handler != nil {
+			return handler(ctx, args)
+		}
+		return 0, nil
+	}
+	return sc.Handler(ctx, args)
+}
+
+func (p *policy) filter(op debugger.FileOp, name string) (debugger.FileHandler, error) {
+	kind := Kind_File
+	switch op {
+	case debugger.FileOp_Socket, debugger.FileOp_Bind, debugger.FileOp_Connect:
+		kind = Kind_Network
+	}
+	d := p.Decide(kind, name)
+	d.Op = opName(op)
+	p.log(d)
+	switch d.Action {
+	case Action_Deny:
+		return nil, d.Errno
+	case Action_Emulate:
+		if handler := p.config.Rules[d.Rule].File; handler != nil {
+			return handler, nil
+		}
+		return nil, fs.ErrNotExist
+	}
+	return nil, nil
+}
+
+func (p *policy) record(kind Kind, subject string) {
+	key := recordKey{kind, subject}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.seen[key]; ok {
+		return
+	}
+	p.seen[key] = struct{}{}
+	p.recorded = append(p.recorded, key)
+}
+
+func (p *policy) log(d Decision) {
+	if p.w == nil {
+		return
+	}
+	action := d.Action.String()
+	if p.config.Mode == Mode_Record {
+		action = "record"
+	}
+	line := fmt.Sprintf("%s %s", action, d.Kind)
+	if d.Op != "" {
+		line += " " + d.Op
+	}
+	line += " " + d.Subject
+	if d.Action == Action_Deny {
+		line += ": " + d.Errno.Error()
+	}
+	if d.Rule >= 0 {
+		line += fmt.Sprintf(" (rule %d)", d.Rule)
+	}
+	p.mu.Lock()
+	io.WriteString(p.w, line+"\n")
+	p.mu.Unlock()
+}
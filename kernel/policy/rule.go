@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+type Action int
+
+const (
+	Action_Allow Action = iota
+	Action_Deny
+	Action_Emulate
+)
+
+type Kind int
+
+const (
+	Kind_Syscall Kind = iota
+	Kind_File
+	Kind_Network
+)
+
+type Rule struct {
+	Kind    Kind                 `json:"kind"`
+	Pattern string               `json:"pattern"`
+	Action  Action               `json:"action"`
+	Errno   kernel.Errno         `json:"errno,omitempty"`
+	Syscall kernel.Handler       `json:"-"`
+	File    debugger.FileHandler `json:"-"`
+}
+
+var (
+	actionNames    = []string{"allow", "deny", "emulate"}
+	kindNames      = []string{"syscall", "file", "network"}
+	opNames        = []string{"open", "stat", "readdir", "mkdir", "readlink", "socket", "bind", "connect"}
+	patternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+)
+
+func (r *Rule) Match(kind Kind, subject string) bool {
+	if r.Kind != kind {
+		return false
+	} else if prefix, ok := strings.CutSuffix(r.Pattern, "**"); ok {
+		return strings.HasPrefix(subject, prefix)
+	}
+	ok, _ := path.Match(r.Pattern, subject)
+	return ok
+}
+
+func (a Action) String() string {
+	if a >= 0 && int(a) < len(actionNames) {
+		return actionNames[a]
+	}
+	return fmt.Sprintf("action(%d)", int(a))
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	return unmarshalName(actionNames, string(text), (*int)(a))
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *Kind) UnmarshalText(text []byte) error {
+	return unmarshalName(kindNames, string(text), (*int)(k))
+}
+
+func unmarshalName(names []string, text string, v *int) error {
+	for i, name := range names {
+		if name == text {
+			*v = i
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", debugger.ErrArgumentInvalid, text)
+}
+
+func opName(op debugger.FileOp) string {
+	if op >= 0 && int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("op(%d)", int(op))
+}
@@ -0,0 +1,98 @@
+package procfs
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/wnxd/microdbg/emulator"
+)
+
+func (h *handler) maps(tid int) ([]byte, error) {
+	regions, err := h.dbg.Emulator().MemRegions()
+	if err != nil {
+		return nil, err
+	}
+	width := int(h.dbg.PointerSize() * 2)
+	column := 25 + width*3 - 1
+	var buf bytes.Buffer
+	for _, region := range regions {
+		var name string
+		var offset uint64
+		if module, err := h.dbg.FindModuleByAddr(region.Addr); err == nil {
+			name, offset = module.Name(), region.Addr-module.BaseAddr()
+		}
+		line := fmt.Sprintf("%0*x-%0*x %s %08x 00:00 0", width, region.Addr, width, region.Addr+region.Size, perms(region.Prot), offset)
+		if name != "" {
+			line = fmt.Sprintf("%-*s%s", max(column, len(line)+1), line, name)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *handler) status(tid int) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Name:\t%s\n", h.config.Name)
+	fmt.Fprintf(&buf, "State:\tR (running)\n")
+	fmt.Fprintf(&buf, "Tgid:\t%d\n", h.config.Pid)
+	fmt.Fprintf(&buf, "Pid:\t%d\n", tid)
+	fmt.Fprintf(&buf, "PPid:\t0\n")
+	fmt.Fprintf(&buf, "TracerPid:\t0\n")
+	fmt.Fprintf(&buf, "Uid:\t0\t0\t0\t0\n")
+	fmt.Fprintf(&buf, "Gid:\t0\t0\t0\t0\n")
+	fmt.Fprintf(&buf, "Threads:\t1\n")
+	fmt.Fprintf(&buf, "SigQ:\t0/0\n")
+	fmt.Fprintf(&buf, "SigBlk:\t0000000000000000\n")
+	fmt.Fprintf(&buf, "Cpus_allowed_list:\t0-%d\n", h.config.CPUs-1)
+	return buf.Bytes(), nil
+}
+
+func (h *handler) stat(tid int) ([]byte, error) {
+	return fmt.Appendf(nil, "%d (%s) R 0 %d %d 0 -1 4194560 0 0 0 0 0 0 0 0 20 0 1 0 0 0 0\n", tid, h.config.Name, h.config.Pid, h.config.Pid), nil
+}
+
+func (h *handler) cmdline(tid int) ([]byte, error) {
+	args := h.config.Cmdline
+	if len(args) == 0 {
+		args = []string{h.config.Name}
+	}
+	return []byte(strings.Join(args, "\x00") + "\x00"), nil
+}
+
+func (h *handler) cpuinfo(tid int) ([]byte, error) {
+	var buf bytes.Buffer
+	for i := range h.config.CPUs {
+		fmt.Fprintf(&buf, "processor\t: %d\n", i)
+		switch h.dbg.Arch() {
+		case emulator.ARCH_ARM64:
+			fmt.Fprintf(&buf, "BogoMIPS\t: 48.00\n")
+			fmt.Fprintf(&buf, "Features\t: fp asimd evtstrm aes pmull sha1 sha2 crc32\n")
+			fmt.Fprintf(&buf, "CPU implementer\t: 0x41\nCPU architecture: 8\nCPU variant\t: 0x0\nCPU part\t: 0xd03\nCPU revision\t: 4\n")
+		case emulator.ARCH_ARM:
+			fmt.Fprintf(&buf, "model name\t: ARMv7 Processor rev 4 (v7l)\n")
+			fmt.Fprintf(&buf, "BogoMIPS\t: 48.00\n")
+			fmt.Fprintf(&buf, "Features\t: half thumb fastmult vfp edsp neon vfpv3 tls vfpv4 idiva idivt\n")
+			fmt.Fprintf(&buf, "CPU implementer\t: 0x41\nCPU architecture: 7\nCPU variant\t: 0x0\nCPU part\t: 0xc07\nCPU revision\t: 4\n")
+		default:
+			fmt.Fprintf(&buf, "model name\t: Virtual CPU\n")
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func perms(prot emulator.MemProt) string {
+	b := []byte("---p")
+	if prot&emulator.MEM_PROT_READ != 0 {
+		b[0] = 'r'
+	}
+	if prot&emulator.MEM_PROT_WRITE != 0 {
+		b[1] = 'w'
+	}
+	if prot&emulator.MEM_PROT_EXEC != 0 {
+		b[2] = 'x'
+	}
+	return string(b)
+}
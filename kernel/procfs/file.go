@@ -0,0 +1,126 @@
+package procfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+type file struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+type stream struct {
+	mu  sync.Mutex
+	src *rand.ChaCha8
+}
+
+type device struct {
+	info   fileInfo
+	stream *stream
+}
+
+func newFile(name string, data []byte, modTime time.Time) *file {
+	return &file{
+		Reader: bytes.NewReader(data),
+		info:   fileInfo{name: name, size: int64(len(data)), mode: 0o444, modTime: modTime},
+	}
+}
+
+func newStream(seed uint64) *stream {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	return &stream{src: rand.NewChaCha8(key)}
+}
+
+func (h *handler) openRandom(tid int, flag filesystem.FileFlag) (filesystem.File, error) {
+	return h.newDevice("urandom", h.random), nil
+}
+
+func (h *handler) openNull(tid int, flag filesystem.FileFlag) (filesystem.File, error) {
+	return h.newDevice("null", nil), nil
+}
+
+func (h *handler) openZero(tid int, flag filesystem.FileFlag) (filesystem.File, error) {
+	return h.newDevice("zero", nil), nil
+}
+
+func (h *handler) newDevice(name string, s *stream) *device {
+	info := fileInfo{name: name, mode: fs.ModeDevice | fs.ModeCharDevice | 0o666, modTime: h.dbg.Clock().Now()}
+	return &device{info: info, stream: s}
+}
+
+func (f *file) Close() error {
+	return nil
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return &f.info, nil
+}
+
+func (s *stream) Read(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Read(b)
+}
+
+func (d *device) Close() error {
+	return nil
+}
+
+func (d *device) Stat() (fs.FileInfo, error) {
+	return &d.info, nil
+}
+
+func (d *device) Read(b []byte) (int, error) {
+	switch {
+	case d.stream != nil:
+		return d.stream.Read(b)
+	case d.info.name == "null":
+		return 0, io.EOF
+	}
+	clear(b)
+	return len(b), nil
+}
+
+func (d *device) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	return fi.mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (fi *fileInfo) Sys() any {
+	return nil
+}
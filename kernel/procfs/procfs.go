@@ -0,0 +1,229 @@
+package procfs
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+const taskDir = "/proc/self/task"
+
+type Generator = func(tid int) ([]byte, error)
+
+type Config struct {
+	Pid     int
+	Name    string
+	Exe     string
+	Cmdline []string
+	Seed    uint64
+	CPUs    int
+}
+
+type Handler interface {
+	debugger.FileHandler
+	Set(name string, gen Generator)
+	Remove(name string)
+}
+
+type opener = func(tid int, flag filesystem.FileFlag) (filesystem.File, error)
+
+type handler struct {
+	debugger.DefaultFileHandler
+	dbg     debugger.Debugger
+	config  Config
+	mu      sync.RWMutex
+	entries map[string]opener
+	random  *stream
+}
+
+func New(dbg debugger.Debugger, config Config) Handler {
+	if config.Pid <= 0 {
+		config.Pid = 1
+	}
+	if config.Name == "" {
+		config.Name = "microdbg"
+	}
+	if config.CPUs <= 0 {
+		config.CPUs = 1
+	}
+	h := &handler{
+		dbg:     dbg,
+		config:  config,
+		entries: make(map[string]opener),
+		random:  newStream(config.Seed),
+	}
+	h.Set("/proc/self/maps", h.maps)
+	h.Set("/proc/self/status", h.status)
+	h.Set("/proc/self/stat", h.stat)
+	h.Set("/proc/self/cmdline", h.cmdline)
+	h.Set("/proc/cpuinfo", h.cpuinfo)
+	h.entries["/dev/urandom"] = h.openRandom
+	h.entries["/dev/random"] = h.openRandom
+	h.entries["/dev/null"] = h.openNull
+	h.entries["/dev/zero"] = h.openZero
+	return h
+}
+
+func (h *handler) Set(name string, gen Generator) {
+	name = path.Clean(name)
+	h.mu.Lock()
+	h.entries[name] = func(tid int, flag filesystem.FileFlag) (filesystem.File, error) {
+		if flag&(filesystem.O_WRONLY|filesystem.O_RDWR) != 0 {
+			return nil, fs.ErrPermission
+		}
+		data, err := gen(tid)
+		if err != nil {
+			return nil, err
+		}
+		return newFile(path.Base(name), data, h.dbg.Clock().Now()), nil
+	}
+	h.mu.Unlock()
+}
+
+func (h *handler) Remove(name string) {
+	h.mu.Lock()
+	delete(h.entries, path.Clean(name))
+	h.mu.Unlock()
+}
+
+func (h *handler) OpenFile(name string, flag filesystem.FileFlag, perm fs.FileMode) (filesystem.File, error) {
+	key, tid, ok := h.resolve(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	h.mu.RLock()
+	open, ok := h.entries[key]
+	h.mu.RUnlock()
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return open(tid, flag)
+}
+
+func (h *handler) Stat(name string) (fs.FileInfo, error) {
+	key, tid, ok := h.resolve(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	h.mu.RLock()
+	open, ok := h.entries[key]
+	h.mu.RUnlock()
+	if ok {
+		file, err := open(tid, filesystem.O_RDONLY)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return file.Stat()
+	}
+	if key != taskDir && len(h.children(key)) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return &fileInfo{name: path.Base(name), mode: fs.ModeDir | 0o555, modTime: h.dbg.Clock().Now()}, nil
+}
+
+func (h *handler) ReadDir(name string) ([]fs.DirEntry, error) {
+	key, _, ok := h.resolve(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	var names []string
+	if key == taskDir {
+		for _, tid := range h.tasks() {
+			names = append(names, strconv.Itoa(tid))
+		}
+	} else {
+		names = h.children(key)
+	}
+	if len(names) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	list := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		info, err := h.Stat(path.Join(name, child))
+		if err != nil {
+			continue
+		}
+		list = append(list, fs.FileInfoToDirEntry(info))
+	}
+	return list, nil
+}
+
+func (h *handler) Readlink(name string) (string, error) {
+	switch key, tid, ok := h.resolve(name); {
+	case !ok:
+		return "", fs.ErrNotExist
+	case key == "/proc/self":
+		return "/proc/" + strconv.Itoa(tid), nil
+	case key == "/proc/self/exe":
+		if h.config.Exe != "" {
+			return h.config.Exe, nil
+		}
+	}
+	return "", fs.ErrInvalid
+}
+
+func (h *handler) resolve(name string) (string, int, bool) {
+	name = path.Clean("/" + name)
+	tid := h.config.Pid
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if len(parts) < 2 || parts[0] != "proc" {
+		return name, tid, true
+	}
+	switch parts[1] {
+	case "self", "thread-self":
+	default:
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return name, tid, true
+		} else if !slices.Contains(h.tasks(), n) {
+			return name, tid, false
+		}
+		tid = n
+	}
+	parts[1] = "self"
+	if len(parts) >= 4 && parts[2] == "task" {
+		n, err := strconv.Atoi(parts[3])
+		if err != nil || !slices.Contains(h.tasks(), n) {
+			return name, tid, false
+		}
+		tid = n
+		parts = append(parts[:2], parts[4:]...)
+	}
+	return "/" + strings.Join(parts, "/"), tid, true
+}
+
+func (h *handler) tasks() []int {
+	tids := []int{h.config.Pid}
+	for _, task := range h.dbg.Tasks() {
+		if !slices.Contains(tids, task.ID()) {
+			tids = append(tids, task.ID())
+		}
+	}
+	slices.Sort(tids)
+	return tids
+}
+
+func (h *handler) children(dir string) []string {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var names []string
+	for key := range h.entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		child, _, _ := strings.Cut(key[len(prefix):], "/")
+		if !slices.Contains(names, child) {
+			names = append(names, child)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
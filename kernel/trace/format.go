@@ -0,0 +1,116 @@
+package trace
+
+import (
+	"strconv"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+type param int
+
+const (
+	paramInt param = iota
+	paramUint
+	paramHex
+	paramOct
+	paramFd
+	paramStr
+	paramBuf
+)
+
+const maxString = 32
+
+var signatures = map[string][]param{
+	"exit":            {paramInt},
+	"exit_group":      {paramInt},
+	"read":            {paramFd, paramHex, paramUint},
+	"write":           {paramFd, paramBuf, paramUint},
+	"open":            {paramStr, paramHex, paramOct},
+	"openat":          {paramFd, paramStr, paramHex, paramOct},
+	"close":           {paramFd},
+	"lseek":           {paramFd, paramInt, paramInt},
+	"_llseek":         {paramFd, paramUint, paramUint, paramHex, paramInt},
+	"fstat":           {paramFd, paramHex},
+	"fstat64":         {paramFd, paramHex},
+	"mmap":            {paramHex, paramUint, paramHex, paramHex, paramFd, paramHex},
+	"mmap2":           {paramHex, paramUint, paramHex, paramHex, paramFd, paramHex},
+	"munmap":          {paramHex, paramUint},
+	"mprotect":        {paramHex, paramUint, paramHex},
+	"brk":             {paramHex},
+	"getpid":          {},
+	"clock_gettime":   {paramInt, paramHex},
+	"clock_gettime64": {paramInt, paramHex},
+}
+
+var hexReturns = map[string]bool{"mmap": true, "mmap2": true, "brk": true}
+
+func (t *tracer) formatArgs(ctx debugger.Context, name string, args kernel.Args) []string {
+	params, ok := signatures[name]
+	if !ok {
+		params = []param{paramHex, paramHex, paramHex}
+	}
+	list := make([]string, len(params))
+	for i, p := range params {
+		list[i] = t.formatArg(ctx, p, args, i)
+	}
+	return list
+}
+
+func (t *tracer) formatArg(ctx debugger.Context, p param, args kernel.Args, i int) string {
+	v := args[i]
+	switch p {
+	case paramInt:
+		return strconv.FormatInt(t.int(v), 10)
+	case paramUint:
+		return strconv.FormatUint(t.word(v), 10)
+	case paramOct:
+		return "0" + strconv.FormatUint(t.word(v), 8)
+	case paramFd:
+		if fd := t.int(v); fd == -100 {
+			return "AT_FDCWD"
+		} else {
+			return strconv.FormatInt(fd, 10)
+		}
+	case paramStr:
+		if v == 0 {
+			return "NULL"
+		}
+		s, err := ctx.ToPointer(v).MemReadString()
+		if err != nil {
+			return "0x" + strconv.FormatUint(v, 16)
+		}
+		return quote([]byte(s))
+	case paramBuf:
+		if v == 0 || i+1 >= len(args) {
+			return "0x" + strconv.FormatUint(v, 16)
+		}
+		b, err := ctx.ToPointer(v).MemRead(min(t.word(args[i+1]), maxString+1))
+		if err != nil {
+			return "0x" + strconv.FormatUint(v, 16)
+		}
+		return quote(b)
+	}
+	return "0x" + strconv.FormatUint(t.word(v), 16)
+}
+
+func (t *tracer) word(v uint64) uint64 {
+	if t.dbg.PointerSize() == 4 {
+		return uint64(uint32(v))
+	}
+	return v
+}
+
+func (t *tracer) int(v uint64) int64 {
+	if t.dbg.PointerSize() == 4 {
+		return int64(int32(v))
+	}
+	return int64(v)
+}
+
+func quote(b []byte) string {
+	if len(b) > maxString {
+		return strconv.Quote(string(b[:maxString])) + "..."
+	}
+	return strconv.Quote(string(b))
+}
@@ -0,0 +1,186 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_arm "github.com/wnxd/microdbg/emulator/arm"
+	"github.com/wnxd/microdbg/kernel"
+)
+
+type Format int
+
+const (
+	Format_Text Format = iota
+	Format_JSON
+)
+
+type Config struct {
+	Format  Format
+	Names   []string
+	Numbers []int64
+}
+
+type Tracer interface {
+	io.Closer
+}
+
+type record struct {
+	Task    int      `json:"task"`
+	Caller  string   `json:"caller"`
+	Intno   uint64   `json:"intno"`
+	Syscall string   `json:"syscall,omitempty"`
+	Nr      *int64   `json:"nr,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Ret     *int64   `json:"ret,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Handled bool     `json:"handled"`
+}
+
+type tracer struct {
+	mu     sync.Mutex
+	dbg    debugger.Debugger
+	kernel kernel.Kernel
+	w      io.Writer
+	config Config
+	closer io.Closer
+}
+
+func New(dbg debugger.Debugger, k kernel.Kernel, w io.Writer, config Config) (Tracer, error) {
+	if dbg == nil || w == nil {
+		return nil, debugger.ErrArgumentInvalid
+	}
+	t := &tracer{dbg: dbg, kernel: k, w: w, config: config}
+	t.closer = dbg.AddInterruptTracer(t)
+	return t, nil
+}
+
+func (t *tracer) Close() error {
+	return t.closer.Close()
+}
+
+func (t *tracer) Enter(ctx debugger.Context, intno uint64) {
+	rec := &record{Task: ctx.TaskID(), Caller: t.caller(ctx), Intno: intno}
+	if t.kernel != nil {
+		if nr, args, ok := t.kernel.Decode(ctx, intno); ok {
+			rec.Nr = &nr
+			if sc, ok := t.kernel.Lookup(nr); ok {
+				rec.Syscall = sc.Name
+			} else {
+				rec.Syscall = fmt.Sprintf("syscall_%d", nr)
+			}
+			if !t.match(nr, rec.Syscall) {
+				return
+			}
+			rec.Args = t.formatArgs(ctx, rec.Syscall, args)
+			ctx.LocalStore(t, rec)
+			return
+		}
+	}
+	if len(t.config.Names) == 0 && len(t.config.Numbers) == 0 {
+		ctx.LocalStore(t, rec)
+	}
+}
+
+func (t *tracer) Exit(ctx debugger.Context, intno uint64, result debugger.HookResult) {
+	val, ok := ctx.LocalLoad(t)
+	if !ok {
+		return
+	}
+	ctx.LocalDelete(t)
+	rec := val.(*record)
+	rec.Handled = result == debugger.HookResult_Done
+	if rec.Nr != nil && rec.Handled && !t.exited(ctx) {
+		ret, err := t.kernel.Result(ctx)
+		if err != nil {
+			v := int64(-1)
+			rec.Ret, rec.Error = &v, err.Error()
+		} else {
+			v := t.int(ret)
+			rec.Ret = &v
+		}
+	}
+	t.write(rec)
+}
+
+func (t *tracer) match(nr int64, name string) bool {
+	if len(t.config.Names) == 0 && len(t.config.Numbers) == 0 {
+		return true
+	}
+	return slices.Contains(t.config.Names, name) || slices.Contains(t.config.Numbers, nr)
+}
+
+func (t *tracer) exited(ctx debugger.Context) bool {
+	task, ok := ctx.(debugger.Task)
+	return ok && task.Status() >= debugger.TaskStatus_Done
+}
+
+func (t *tracer) caller(ctx debugger.Context) string {
+	pc, err := ctx.RegRead(ctx.PC())
+	if err != nil {
+		return "?"
+	}
+	switch t.dbg.Arch() {
+	case emulator.ARCH_ARM:
+		if cpsr, err := ctx.RegRead(emu_arm.ARM_REG_CPSR); err == nil && cpsr&0x20 != 0 {
+			pc -= 2
+		} else {
+			pc -= 4
+		}
+	case emulator.ARCH_ARM64:
+		pc -= 4
+	case emulator.ARCH_X86, emulator.ARCH_X86_64:
+		pc -= 2
+	}
+	module, sym, off, err := t.dbg.FindSymbolByAddr(pc)
+	switch {
+	case err == nil:
+		return fmt.Sprintf("%s!%s+0x%x", module.Name(), sym.Name, off)
+	case module != nil:
+		return fmt.Sprintf("%s+0x%x", module.Name(), off)
+	}
+	return fmt.Sprintf("0x%x", pc)
+}
+
+func (t *tracer) write(rec *record) {
+	var line string
+	if t.config.Format == Format_JSON {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return
+		}
+		line = string(b) + "\n"
+	} else {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "[%d] %s ", rec.Task, rec.Caller)
+		if rec.Nr != nil {
+			fmt.Fprintf(&sb, "%s(%s) = ", rec.Syscall, strings.Join(rec.Args, ", "))
+			switch {
+			case rec.Ret == nil:
+				sb.WriteString("?")
+			case rec.Error != "":
+				fmt.Fprintf(&sb, "%d %s", *rec.Ret, rec.Error)
+			case hexReturns[rec.Syscall]:
+				fmt.Fprintf(&sb, "0x%x", t.word(uint64(*rec.Ret)))
+			default:
+				fmt.Fprintf(&sb, "%d", *rec.Ret)
+			}
+		} else {
+			fmt.Fprintf(&sb, "intr(%d)", rec.Intno)
+			if !rec.Handled {
+				sb.WriteString(" unhandled")
+			}
+		}
+		sb.WriteByte('\n')
+		line = sb.String()
+	}
+	t.mu.Lock()
+	io.WriteString(t.w, line)
+	t.mu.Unlock()
+}
@@ -0,0 +1,211 @@
+package libc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+func (l *libc) registerFormat() {
+	l.functions["sprintf"] = l.sprintf
+	l.functions["snprintf"] = l.snprintf
+	l.functions["printf"] = l.printf
+}
+
+type variadicArgs struct {
+	debugger.Args
+}
+
+func variadic(args debugger.Args, calling debugger.Calling) debugger.Args {
+	if calling == debugger.Calling_Darwin {
+		return variadicArgs{args}
+	}
+	return args
+}
+
+func (va variadicArgs) Extract(args ...any) error {
+	return va.Args.Extract(debugger.Variadic(args))
+}
+
+func (l *libc) sprintf(ctx debugger.Context, data any) {
+	calling, _ := data.(debugger.Calling)
+	args, err := ctx.GetArgs(calling)
+	if err != nil {
+		return
+	}
+	var buf uintptr
+	var format string
+	if args.Extract(&buf, &format) != nil {
+		return
+	}
+	s := l.format(format, variadic(args, calling))
+	ctx.ToPointer(uint64(buf)).MemWrite(append([]byte(s), 0))
+	ctx.RetWrite(int32(len(s)))
+}
+
+func (l *libc) snprintf(ctx debugger.Context, data any) {
+	calling, _ := data.(debugger.Calling)
+	args, err := ctx.GetArgs(calling)
+	if err != nil {
+		return
+	}
+	var buf, size uintptr
+	var format string
+	if args.Extract(&buf, &size, &format) != nil {
+		return
+	}
+	s := l.format(format, variadic(args, calling))
+	if size != 0 {
+		out := []byte(s)[:min(uint64(len(s)), uint64(size)-1)]
+		ctx.ToPointer(uint64(buf)).MemWrite(append(out, 0))
+	}
+	ctx.RetWrite(int32(len(s)))
+}
+
+func (l *libc) printf(ctx debugger.Context, data any) {
+	calling, _ := data.(debugger.Calling)
+	args, err := ctx.GetArgs(calling)
+	if err != nil {
+		return
+	}
+	var format string
+	if args.Extract(&format) != nil {
+		return
+	}
+	s := l.format(format, variadic(args, calling))
+	if file, err := l.dbg.GetFile(1); err == nil {
+		if w, ok := file.(filesystem.WriteFile); ok {
+			w.Write([]byte(s))
+		}
+	}
+	ctx.RetWrite(int32(len(s)))
+}
+
+func (l *libc) format(format string, args debugger.Args) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		spec := "%"
+		for i++; i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0; i++ {
+			spec += format[i : i+1]
+		}
+		spec += l.number(format, &i, args)
+		precision := i < len(format) && format[i] == '.'
+		if precision {
+			i++
+			spec += "." + l.number(format, &i, args)
+		}
+		var length string
+		for ; i < len(format) && strings.IndexByte("hlLqjzt", format[i]) >= 0; i++ {
+			length += format[i : i+1]
+		}
+		if i >= len(format) {
+			break
+		}
+		switch verb := format[i]; verb {
+		case '%':
+			sb.WriteByte('%')
+		case 'd', 'i':
+			fmt.Fprintf(&sb, spec+"d", l.signed(length, args))
+		case 'u', 'x', 'X', 'o':
+			if verb == 'u' {
+				verb = 'd'
+			}
+			fmt.Fprintf(&sb, spec+string(verb), l.unsigned(length, args))
+		case 'c':
+			var v int32
+			args.Extract(&v)
+			fmt.Fprintf(&sb, spec+"s", string([]byte{byte(v)}))
+		case 's':
+			var s string
+			if args.Extract(&s) != nil {
+				s = "(null)"
+			}
+			fmt.Fprintf(&sb, spec+"s", s)
+		case 'p':
+			var v uintptr
+			args.Extract(&v)
+			fmt.Fprintf(&sb, "%#x", uint64(v))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			var v float64
+			args.Extract(&v)
+			if !precision && (verb == 'g' || verb == 'G') {
+				spec += ".6"
+			}
+			fmt.Fprintf(&sb, spec+strings.ToLower(string(verb)), v)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(verb)
+		}
+	}
+	return sb.String()
+}
+
+func (l *libc) number(format string, i *int, args debugger.Args) string {
+	if *i < len(format) && format[*i] == '*' {
+		*i++
+		var v int32
+		args.Extract(&v)
+		return strconv.Itoa(int(v))
+	}
+	begin := *i
+	for *i < len(format) && format[*i] >= '0' && format[*i] <= '9' {
+		*i++
+	}
+	return format[begin:*i]
+}
+
+func (l *libc) signed(length string, args debugger.Args) int64 {
+	switch l.size(length) {
+	case 8:
+		var v int64
+		args.Extract(&v)
+		return v
+	default:
+		var v int32
+		args.Extract(&v)
+		switch length {
+		case "hh":
+			return int64(int8(v))
+		case "h":
+			return int64(int16(v))
+		}
+		return int64(v)
+	}
+}
+
+func (l *libc) unsigned(length string, args debugger.Args) uint64 {
+	switch l.size(length) {
+	case 8:
+		var v uint64
+		args.Extract(&v)
+		return v
+	default:
+		var v uint32
+		args.Extract(&v)
+		switch length {
+		case "hh":
+			return uint64(uint8(v))
+		case "h":
+			return uint64(uint16(v))
+		}
+		return uint64(v)
+	}
+}
+
+func (l *libc) size(length string) uint64 {
+	switch length {
+	case "ll", "q", "j", "L":
+		return 8
+	case "l", "z", "t":
+		return l.dbg.PointerSize()
+	}
+	return 4
+}
@@ -0,0 +1,104 @@
+package libc
+
+import (
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type Libc interface {
+	io.Closer
+	Register(name string, callback debugger.ControlCallback)
+	Resolve(name string) (uint64, error)
+	Names() []string
+}
+
+type stub struct {
+	name    string
+	calling debugger.Calling
+}
+
+type libc struct {
+	mu        sync.Mutex
+	dbg       debugger.Debugger
+	functions map[string]debugger.ControlCallback
+	stubs     map[string]debugger.ControlHandler
+	mutexes   map[uint64]*mutex
+	released  chan struct{}
+}
+
+func New(dbg debugger.Debugger) Libc {
+	l := &libc{
+		dbg:       dbg,
+		functions: make(map[string]debugger.ControlCallback),
+		stubs:     make(map[string]debugger.ControlHandler),
+		mutexes:   make(map[uint64]*mutex),
+		released:  make(chan struct{}),
+	}
+	l.registerMemory()
+	l.registerString()
+	l.registerFormat()
+	l.registerPthread()
+	return l
+}
+
+func (l *libc) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, stub := range l.stubs {
+		stub.Close()
+	}
+	clear(l.stubs)
+	return nil
+}
+
+func (l *libc) Register(name string, callback debugger.ControlCallback) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.functions[name] = callback
+}
+
+func (l *libc) Resolve(name string) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if stub, ok := l.stubs[name]; ok {
+		return stub.Addr(), nil
+	}
+	s := stub{name: name, calling: debugger.Calling_Default}
+	if _, ok := l.functions[name]; !ok {
+		s.name = strings.TrimPrefix(name, "_")
+		if _, ok = l.functions[s.name]; !ok {
+			return 0, debugger.ErrSymbolNotFound
+		} else if l.dbg.Arch() == emulator.ARCH_ARM64 {
+			s.calling = debugger.Calling_Darwin
+		}
+	}
+	handler, err := l.dbg.AddControl(l.handleStub, s)
+	if err != nil {
+		return 0, err
+	}
+	l.stubs[name] = handler
+	return handler.Addr(), nil
+}
+
+func (l *libc) Names() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Sorted(maps.Keys(l.functions))
+}
+
+func (l *libc) handleStub(ctx debugger.Context, data any) {
+	s := data.(stub)
+	l.mu.Lock()
+	callback := l.functions[s.name]
+	l.mu.Unlock()
+	if callback != nil {
+		callback(ctx, s.calling)
+	}
+	ctx.Return()
+}
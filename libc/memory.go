@@ -0,0 +1,81 @@
+package libc
+
+import "github.com/wnxd/microdbg/debugger"
+
+func (l *libc) registerMemory() {
+	l.functions["malloc"] = l.malloc
+	l.functions["free"] = l.free
+	l.functions["calloc"] = l.calloc
+	l.functions["realloc"] = l.realloc
+}
+
+func (l *libc) malloc(ctx debugger.Context, data any) {
+	var size uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &size) != nil {
+		return
+	}
+	ctx.RetWrite(l.alloc(uint64(size)))
+}
+
+func (l *libc) free(ctx debugger.Context, data any) {
+	var ptr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &ptr) != nil || ptr == 0 {
+		return
+	}
+	l.dbg.MemFree(uint64(ptr))
+}
+
+func (l *libc) calloc(ctx debugger.Context, data any) {
+	var count, size uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &count, &size) != nil {
+		return
+	}
+	n := uint64(count) * uint64(size)
+	if size != 0 && n/uint64(size) != uint64(count) {
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	ptr := l.alloc(n)
+	if ptr != 0 {
+		ctx.ToPointer(uint64(ptr)).MemWrite(make([]byte, n))
+	}
+	ctx.RetWrite(ptr)
+}
+
+func (l *libc) realloc(ctx debugger.Context, data any) {
+	var ptr, size uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &ptr, &size) != nil {
+		return
+	}
+	switch {
+	case ptr == 0:
+		ctx.RetWrite(l.alloc(uint64(size)))
+		return
+	case size == 0:
+		l.dbg.MemFree(uint64(ptr))
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	old := l.dbg.MemSize(uint64(ptr))
+	if old >= uint64(size) {
+		ctx.RetWrite(ptr)
+		return
+	}
+	newPtr := l.alloc(uint64(size))
+	if newPtr != 0 {
+		buf, err := ctx.ToPointer(uint64(ptr)).MemRead(old)
+		if err == nil {
+			ctx.ToPointer(uint64(newPtr)).MemWrite(buf)
+		}
+		l.dbg.MemFree(uint64(ptr))
+	}
+	ctx.RetWrite(newPtr)
+}
+
+func (l *libc) alloc(size uint64) uintptr {
+	addr, err := l.dbg.MemAlloc(max(size, 1))
+	if err != nil {
+		return 0
+	}
+	return uintptr(addr)
+}
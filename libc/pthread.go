@@ -0,0 +1,171 @@
+package libc
+
+import (
+	"encoding/binary"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+const (
+	EPERM   = 1
+	EBUSY   = 16
+	EDEADLK = 35
+)
+
+const (
+	PTHREAD_MUTEX_NORMAL = iota
+	PTHREAD_MUTEX_RECURSIVE
+	PTHREAD_MUTEX_ERRORCHECK
+)
+
+const (
+	mutexTypeShift      = 14
+	mutexTypeMask       = 3
+	darwinMutexSigMask  = 0xffffff00
+	darwinMutexSig      = 0x32aaab00
+	darwinRecursiveSig  = 0x32aaaba2
+	darwinErrorCheckSig = 0x32aaaba1
+	mutexAttrTypeMask   = 0xf
+)
+
+type mutex struct {
+	typ   int
+	owner int
+	count int
+}
+
+func (l *libc) registerPthread() {
+	l.functions["pthread_mutex_init"] = l.mutexInit
+	l.functions["pthread_mutex_destroy"] = l.mutexDestroy
+	l.functions["pthread_mutex_lock"] = l.mutexLock
+	l.functions["pthread_mutex_trylock"] = l.mutexTrylock
+	l.functions["pthread_mutex_unlock"] = l.mutexUnlock
+}
+
+func (l *libc) mutexInit(ctx debugger.Context, data any) {
+	var addr, attr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &addr, &attr) != nil {
+		return
+	}
+	typ := PTHREAD_MUTEX_NORMAL
+	if attr != 0 {
+		if buf, err := ctx.ToPointer(uint64(attr)).MemRead(4); err == nil {
+			typ = int(binary.LittleEndian.Uint32(buf) & mutexAttrTypeMask)
+		}
+	}
+	l.mu.Lock()
+	l.mutexes[uint64(addr)] = &mutex{typ: typ}
+	l.mu.Unlock()
+	ctx.RetWrite(int32(0))
+}
+
+func (l *libc) mutexDestroy(ctx debugger.Context, data any) {
+	var addr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &addr) != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m, ok := l.mutexes[uint64(addr)]; ok && m.count > 0 {
+		ctx.RetWrite(int32(EBUSY))
+		return
+	}
+	delete(l.mutexes, uint64(addr))
+	ctx.RetWrite(int32(0))
+}
+
+func (l *libc) mutexLock(ctx debugger.Context, data any) {
+	var addr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &addr) != nil {
+		return
+	}
+	var done <-chan struct{}
+	if task, ok := ctx.(debugger.Task); ok {
+		done = task.Done()
+	}
+	for {
+		l.mu.Lock()
+		m := l.mutex(ctx, uint64(addr))
+		if errno, ok := m.acquire(ctx.TaskID()); ok {
+			l.mu.Unlock()
+			ctx.RetWrite(int32(errno))
+			return
+		}
+		released := l.released
+		l.mu.Unlock()
+		select {
+		case <-released:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (l *libc) mutexTrylock(ctx debugger.Context, data any) {
+	var addr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &addr) != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.mutex(ctx, uint64(addr))
+	if errno, ok := m.acquire(ctx.TaskID()); !ok || errno == EDEADLK {
+		ctx.RetWrite(int32(EBUSY))
+	} else {
+		ctx.RetWrite(int32(errno))
+	}
+}
+
+func (l *libc) mutexUnlock(ctx debugger.Context, data any) {
+	var addr uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &addr) != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.mutexes[uint64(addr)]
+	if !ok || m.count == 0 || m.owner != ctx.TaskID() {
+		ctx.RetWrite(int32(EPERM))
+		return
+	}
+	if m.count--; m.count == 0 {
+		m.owner = 0
+		close(l.released)
+		l.released = make(chan struct{})
+	}
+	ctx.RetWrite(int32(0))
+}
+
+func (l *libc) mutex(ctx debugger.Context, addr uint64) *mutex {
+	if m, ok := l.mutexes[addr]; ok {
+		return m
+	}
+	m := &mutex{typ: PTHREAD_MUTEX_NORMAL}
+	if buf, err := ctx.ToPointer(addr).MemRead(4); err == nil {
+		switch v := binary.LittleEndian.Uint32(buf); {
+		case v == darwinRecursiveSig:
+			m.typ = PTHREAD_MUTEX_RECURSIVE
+		case v == darwinErrorCheckSig:
+			m.typ = PTHREAD_MUTEX_ERRORCHECK
+		case v&darwinMutexSigMask == darwinMutexSig:
+		default:
+			m.typ = int(v >> mutexTypeShift & mutexTypeMask)
+		}
+	}
+	l.mutexes[addr] = m
+	return m
+}
+
+func (m *mutex) acquire(tid int) (int, bool) {
+	switch {
+	case m.count == 0:
+		m.owner, m.count = tid, 1
+		return 0, true
+	case m.owner != tid:
+		return 0, false
+	case m.typ == PTHREAD_MUTEX_RECURSIVE:
+		m.count++
+		return 0, true
+	}
+	return EDEADLK, true
+}
@@ -0,0 +1,196 @@
+package libc
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+func (l *libc) registerString() {
+	l.functions["memcpy"] = l.memcpy
+	l.functions["memmove"] = l.memcpy
+	l.functions["memset"] = l.memset
+	l.functions["memcmp"] = l.memcmp
+	l.functions["strlen"] = l.strlen
+	l.functions["strcmp"] = l.strcmp
+	l.functions["strncmp"] = l.strncmp
+	l.functions["strcpy"] = l.strcpy
+	l.functions["strncpy"] = l.strncpy
+	l.functions["strcat"] = l.strcat
+	l.functions["strchr"] = l.strchr
+	l.functions["strrchr"] = l.strrchr
+	l.functions["strstr"] = l.strstr
+	l.functions["strdup"] = l.strdup
+}
+
+func (l *libc) memcpy(ctx debugger.Context, data any) {
+	var dst, src, n uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &dst, &src, &n) != nil {
+		return
+	}
+	if n != 0 {
+		buf, err := ctx.ToPointer(uint64(src)).MemRead(uint64(n))
+		if err == nil {
+			ctx.ToPointer(uint64(dst)).MemWrite(buf)
+		}
+	}
+	ctx.RetWrite(dst)
+}
+
+func (l *libc) memset(ctx debugger.Context, data any) {
+	var dst uintptr
+	var c int32
+	var n uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &dst, &c, &n) != nil {
+		return
+	}
+	if n != 0 {
+		ctx.ToPointer(uint64(dst)).MemWrite(bytes.Repeat([]byte{byte(c)}, int(n)))
+	}
+	ctx.RetWrite(dst)
+}
+
+func (l *libc) memcmp(ctx debugger.Context, data any) {
+	var a, b, n uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &a, &b, &n) != nil {
+		return
+	}
+	x, err1 := ctx.ToPointer(uint64(a)).MemRead(uint64(n))
+	y, err2 := ctx.ToPointer(uint64(b)).MemRead(uint64(n))
+	if err1 != nil || err2 != nil {
+		ctx.RetWrite(int32(0))
+		return
+	}
+	ctx.RetWrite(diff(x, y))
+}
+
+func (l *libc) strlen(ctx debugger.Context, data any) {
+	var s string
+	if ctx.ArgExtract(debugger.Calling_Default, &s) != nil {
+		return
+	}
+	ctx.RetWrite(uintptr(len(s)))
+}
+
+func (l *libc) strcmp(ctx debugger.Context, data any) {
+	var a, b string
+	if ctx.ArgExtract(debugger.Calling_Default, &a, &b) != nil {
+		return
+	}
+	ctx.RetWrite(diff(append([]byte(a), 0), append([]byte(b), 0)))
+}
+
+func (l *libc) strncmp(ctx debugger.Context, data any) {
+	var a, b string
+	var n uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &a, &b, &n) != nil {
+		return
+	}
+	x, y := append([]byte(a), 0), append([]byte(b), 0)
+	x, y = x[:min(uint64(len(x)), uint64(n))], y[:min(uint64(len(y)), uint64(n))]
+	ctx.RetWrite(diff(x, y))
+}
+
+func (l *libc) strcpy(ctx debugger.Context, data any) {
+	var dst uintptr
+	var src string
+	if ctx.ArgExtract(debugger.Calling_Default, &dst, &src) != nil {
+		return
+	}
+	ctx.ToPointer(uint64(dst)).MemWrite(append([]byte(src), 0))
+	ctx.RetWrite(dst)
+}
+
+func (l *libc) strncpy(ctx debugger.Context, data any) {
+	var dst uintptr
+	var src string
+	var n uintptr
+	if ctx.ArgExtract(debugger.Calling_Default, &dst, &src, &n) != nil {
+		return
+	}
+	buf := make([]byte, n)
+	copy(buf, src)
+	ctx.ToPointer(uint64(dst)).MemWrite(buf)
+	ctx.RetWrite(dst)
+}
+
+func (l *libc) strcat(ctx debugger.Context, data any) {
+	var dst uintptr
+	var src string
+	if ctx.ArgExtract(debugger.Calling_Default, &dst, &src) != nil {
+		return
+	}
+	s, err := ctx.ToPointer(uint64(dst)).MemReadString()
+	if err == nil {
+		ctx.ToPointer(uint64(dst) + uint64(len(s))).MemWrite(append([]byte(src), 0))
+	}
+	ctx.RetWrite(dst)
+}
+
+func (l *libc) strchr(ctx debugger.Context, data any) {
+	l.search(ctx, strings.IndexByte)
+}
+
+func (l *libc) strrchr(ctx debugger.Context, data any) {
+	l.search(ctx, strings.LastIndexByte)
+}
+
+func (l *libc) search(ctx debugger.Context, index func(string, byte) int) {
+	var ptr uintptr
+	var c int32
+	if ctx.ArgExtract(debugger.Calling_Default, &ptr, &c) != nil {
+		return
+	}
+	s, err := ctx.ToPointer(uint64(ptr)).MemReadString()
+	if err != nil {
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	i := index(s+"\x00", byte(c))
+	if i < 0 {
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	ctx.RetWrite(ptr + uintptr(i))
+}
+
+func (l *libc) strstr(ctx debugger.Context, data any) {
+	var ptr uintptr
+	var needle string
+	if ctx.ArgExtract(debugger.Calling_Default, &ptr, &needle) != nil {
+		return
+	}
+	s, err := ctx.ToPointer(uint64(ptr)).MemReadString()
+	if err != nil {
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	i := strings.Index(s, needle)
+	if i < 0 {
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	ctx.RetWrite(ptr + uintptr(i))
+}
+
+func (l *libc) strdup(ctx debugger.Context, data any) {
+	var s string
+	if ctx.ArgExtract(debugger.Calling_Default, &s) != nil {
+		return
+	}
+	ptr := l.alloc(uint64(len(s)) + 1)
+	if ptr != 0 {
+		ctx.ToPointer(uint64(ptr)).MemWrite(append([]byte(s), 0))
+	}
+	ctx.RetWrite(ptr)
+}
+
+func diff(a, b []byte) int32 {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return int32(a[i]) - int32(b[i])
+		}
+	}
+	return 0
+}
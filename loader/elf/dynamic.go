@@ -0,0 +1,144 @@
+package elf
+
+import (
+	"bytes"
+	std_elf "debug/elf"
+)
+
+const (
+	DT_RELRSZ         std_elf.DynTag = 35
+	DT_RELR           std_elf.DynTag = 36
+	DT_ANDROID_REL    std_elf.DynTag = 0x6000000f
+	DT_ANDROID_RELSZ  std_elf.DynTag = 0x60000010
+	DT_ANDROID_RELA   std_elf.DynTag = 0x60000011
+	DT_ANDROID_RELASZ std_elf.DynTag = 0x60000012
+)
+
+type symbol struct {
+	name  string
+	value uint64
+	size  uint64
+	info  uint8
+	shndx std_elf.SectionIndex
+}
+
+func (m *module) parseDynamic() error {
+	for _, val := range m.dynamic[std_elf.DT_NEEDED] {
+		m.needed = append(m.needed, m.dynString(val))
+	}
+	err := m.parseSymbols()
+	if err != nil {
+		return err
+	}
+	return m.parseRelocs()
+}
+
+func (m *module) dynValue(tag std_elf.DynTag) (uint64, bool) {
+	if vals := m.dynamic[tag]; len(vals) > 0 {
+		return vals[0], true
+	}
+	return 0, false
+}
+
+func (m *module) dynData(tag, sizeTag std_elf.DynTag) []byte {
+	addr, ok := m.dynValue(tag)
+	if !ok {
+		return nil
+	}
+	size, _ := m.dynValue(sizeTag)
+	off := m.offset(addr)
+	if off+size > uint64(len(m.image)) {
+		return nil
+	}
+	return m.image[off : off+size]
+}
+
+func (m *module) dynString(off uint64) string {
+	strtab, ok := m.dynValue(std_elf.DT_STRTAB)
+	if !ok {
+		return ""
+	}
+	data := m.image[m.offset(strtab)+off:]
+	if i := bytes.IndexByte(data, 0); i != -1 {
+		data = data[:i]
+	}
+	return string(data)
+}
+
+func (m *module) symbolCount() int {
+	if addr, ok := m.dynValue(std_elf.DT_HASH); ok {
+		return int(m.order.Uint32(m.image[m.offset(addr)+4:]))
+	}
+	addr, ok := m.dynValue(std_elf.DT_GNU_HASH)
+	if !ok {
+		return 0
+	}
+	data := m.image[m.offset(addr):]
+	nbucket := int(m.order.Uint32(data))
+	symoffset := int(m.order.Uint32(data[4:]))
+	bloomSize := int(m.order.Uint32(data[8:]))
+	buckets := data[16+bloomSize*m.wordSize():]
+	chains := buckets[nbucket*4:]
+	last := 0
+	for i := 0; i < nbucket; i++ {
+		last = max(last, int(m.order.Uint32(buckets[i*4:])))
+	}
+	if last < symoffset {
+		return symoffset
+	}
+	for m.order.Uint32(chains[(last-symoffset)*4:])&1 == 0 {
+		last++
+	}
+	return last + 1
+}
+
+func (m *module) parseSymbols() error {
+	addr, ok := m.dynValue(std_elf.DT_SYMTAB)
+	if !ok {
+		return nil
+	}
+	count := m.symbolCount()
+	size := std_elf.Sym32Size
+	if m.class == std_elf.ELFCLASS64 {
+		size = std_elf.Sym64Size
+	}
+	off := m.offset(addr)
+	if off+uint64(count*size) > uint64(len(m.image)) {
+		return ErrSymbolInvalid
+	}
+	data := m.image[off:]
+	m.symbols = make([]symbol, count)
+	m.exports = make(map[string]uint64)
+	for i := range m.symbols {
+		b := data[i*size:]
+		sym := &m.symbols[i]
+		if m.class == std_elf.ELFCLASS64 {
+			sym.name = m.dynString(uint64(m.order.Uint32(b)))
+			sym.info = b[4]
+			sym.shndx = std_elf.SectionIndex(m.order.Uint16(b[6:]))
+			sym.value = m.order.Uint64(b[8:])
+			sym.size = m.order.Uint64(b[16:])
+		} else {
+			sym.name = m.dynString(uint64(m.order.Uint32(b)))
+			sym.value = uint64(m.order.Uint32(b[4:]))
+			sym.size = uint64(m.order.Uint32(b[8:]))
+			sym.info = b[12]
+			sym.shndx = std_elf.SectionIndex(m.order.Uint16(b[14:]))
+		}
+		if sym.name == "" || sym.shndx == std_elf.SHN_UNDEF {
+			continue
+		}
+		switch std_elf.ST_BIND(sym.info) {
+		case std_elf.STB_GLOBAL, std_elf.STB_WEAK:
+			m.exports[sym.name] = m.symbolAddr(sym)
+		}
+	}
+	return nil
+}
+
+func (m *module) symbolAddr(sym *symbol) uint64 {
+	if sym.shndx == std_elf.SHN_ABS {
+		return sym.value
+	}
+	return m.bias + sym.value
+}
@@ -0,0 +1,71 @@
+package elf
+
+import (
+	"bytes"
+	std_elf "debug/elf"
+	"io/fs"
+	"path"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type Module interface {
+	debugger.Module
+	debugger.SymbolIter
+	Needed() []string
+}
+
+func Load(dbg debugger.Debugger, fsys filesystem.FS, name string) (Module, error) {
+	m, err := open(dbg, fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	err = m.link(m.lookup)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func open(dbg debugger.Debugger, fsys filesystem.FS, name string) (*module, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := std_elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var arch emulator.Arch
+	switch f.Machine {
+	case std_elf.EM_ARM:
+		arch = emulator.ARCH_ARM
+	case std_elf.EM_AARCH64:
+		arch = emulator.ARCH_ARM64
+	default:
+		return nil, emulator.ErrArchUnsupported
+	}
+	if arch != dbg.Arch() {
+		return nil, emulator.ErrArchMismatch
+	}
+	m := &module{
+		dbg:     dbg,
+		name:    path.Base(name),
+		class:   f.Class,
+		machine: f.Machine,
+		order:   f.ByteOrder,
+	}
+	err = m.mapImage(f)
+	if err != nil {
+		return nil, err
+	}
+	err = m.parseDynamic()
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
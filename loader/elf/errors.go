@@ -0,0 +1,9 @@
+package elf
+
+import "errors"
+
+var (
+	ErrSegmentNotFound = errors.New("load segment not found")
+	ErrSymbolInvalid   = errors.New("symbol invalid")
+	ErrRelocInvalid    = errors.New("relocation invalid")
+)
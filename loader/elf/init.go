@@ -0,0 +1,107 @@
+package elf
+
+import (
+	"context"
+	std_elf "debug/elf"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+func (m *module) Init(ctx context.Context) error {
+	if m.initialized {
+		return nil
+	}
+	m.initialized = true
+	for _, place := range m.irelative {
+		resolver, err := m.dbg.ToPointer(place).MemReadPointer()
+		if err != nil {
+			return err
+		}
+		var addr uintptr
+		err = m.call(ctx, resolver.Address(), &addr)
+		if err != nil {
+			return err
+		}
+		raw := make([]byte, m.wordSize())
+		m.putWord(raw, uint64(addr))
+		err = m.dbg.Emulator().MemWrite(place, raw)
+		if err != nil {
+			return err
+		}
+	}
+	err := m.callArray(ctx, std_elf.DT_PREINIT_ARRAY, std_elf.DT_PREINIT_ARRAYSZ)
+	if err != nil {
+		return err
+	}
+	if addr, ok := m.dynValue(std_elf.DT_INIT); ok && addr != 0 {
+		err = m.callInit(ctx, std_elf.DT_INIT.String(), 0, m.bias+addr)
+		if err != nil {
+			return err
+		}
+	}
+	return m.callArray(ctx, std_elf.DT_INIT_ARRAY, std_elf.DT_INIT_ARRAYSZ)
+}
+
+func (m *module) callArray(ctx context.Context, tag, sizeTag std_elf.DynTag) error {
+	addr, ok := m.dynValue(tag)
+	if !ok {
+		return nil
+	}
+	size, _ := m.dynValue(sizeTag)
+	ws := uint64(m.wordSize())
+	data, err := m.dbg.Emulator().MemRead(m.bias+addr, size)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i+ws <= size; i += ws {
+		fn := m.word(data[i:])
+		if fn == 0 || fn == ^uint64(0) || fn == uint64(^uint32(0)) {
+			continue
+		}
+		err = m.callInit(ctx, tag.String(), int(i/ws), fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *module) callInit(ctx context.Context, name string, index int, addr uint64) error {
+	err := ctx.Err()
+	if err != nil {
+		return context.Cause(ctx)
+	}
+	task, err := m.dbg.CreateTask(ctx)
+	if err != nil {
+		return err
+	}
+	defer task.Close()
+	err = m.dbg.CallTaskOf(task, addr)
+	if err != nil {
+		return err
+	}
+	err = task.SyncRun()
+	if err == nil {
+		return nil
+	} else if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return debugger.NewInitException(task.Context(), name, index, err)
+}
+
+func (m *module) call(ctx context.Context, addr uint64, ret any) error {
+	task, err := m.dbg.CreateTask(ctx)
+	if err != nil {
+		return err
+	}
+	defer task.Close()
+	err = m.dbg.CallTaskOf(task, addr)
+	if err != nil {
+		return err
+	}
+	err = task.SyncRun()
+	if err != nil {
+		return err
+	}
+	return task.Context().RetExtract(ret)
+}
@@ -0,0 +1,185 @@
+package elf
+
+import (
+	std_elf "debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type UnresolvedCallback = func(module Module, name string) (uint64, error)
+
+type Linker interface {
+	io.Closer
+	Load(name string) (Module, error)
+	AddSearchPath(dir string)
+	AddUnresolved(callback UnresolvedCallback)
+	Stub(callback debugger.ControlCallback, data any) (uint64, error)
+}
+
+type linker struct {
+	mu         sync.Mutex
+	stubMu     sync.Mutex
+	dbg        debugger.Debugger
+	paths      []string
+	unresolved []UnresolvedCallback
+	resolved   map[string]uint64
+	loading    []*module
+	stubs      []debugger.ControlHandler
+}
+
+func NewLinker(dbg debugger.Debugger, paths ...string) Linker {
+	if len(paths) == 0 {
+		switch dbg.Arch() {
+		case emulator.ARCH_ARM:
+			paths = []string{"/system/lib", "/vendor/lib"}
+		case emulator.ARCH_ARM64:
+			paths = []string{"/system/lib64", "/vendor/lib64"}
+		}
+	}
+	return &linker{
+		dbg:      dbg,
+		paths:    paths,
+		resolved: make(map[string]uint64),
+	}
+}
+
+func (l *linker) Close() error {
+	l.mu.Lock()
+	clear(l.resolved)
+	l.mu.Unlock()
+	l.stubMu.Lock()
+	defer l.stubMu.Unlock()
+	for i := len(l.stubs) - 1; i >= 0; i-- {
+		l.stubs[i].Close()
+	}
+	l.stubs = nil
+	return nil
+}
+
+func (l *linker) Load(name string) (Module, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(name)
+}
+
+func (l *linker) AddSearchPath(dir string) {
+	l.mu.Lock()
+	l.paths = append(l.paths, dir)
+	l.mu.Unlock()
+}
+
+func (l *linker) AddUnresolved(callback UnresolvedCallback) {
+	l.mu.Lock()
+	l.unresolved = append(l.unresolved, callback)
+	l.mu.Unlock()
+}
+
+func (l *linker) Stub(callback debugger.ControlCallback, data any) (uint64, error) {
+	ctrl, err := l.dbg.AddControl(func(ctx debugger.Context, data any) {
+		callback(ctx, data)
+		ctx.Return()
+	}, data)
+	if err != nil {
+		return 0, err
+	}
+	l.stubMu.Lock()
+	l.stubs = append(l.stubs, ctrl)
+	l.stubMu.Unlock()
+	return ctrl.Addr(), nil
+}
+
+func (l *linker) load(name string) (Module, error) {
+	m, err := open(l.dbg, l.dbg.GetFS(), name)
+	if err != nil {
+		return nil, err
+	}
+	l.loading = append(l.loading, m)
+	var missing []error
+	for _, needed := range m.needed {
+		if _, err = l.dbg.FindModule(needed); err == nil || l.pending(needed) != nil {
+			err = nil
+			continue
+		}
+		_, err = l.loadNeeded(needed)
+		if err == debugger.ErrModuleNotFound {
+			missing = append(missing, fmt.Errorf("%w: %s", err, needed))
+			err = nil
+		} else if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = m.link(func(sym *symbol) (uint64, error) {
+			return l.resolve(m, sym)
+		})
+		if err != nil && len(missing) > 0 {
+			err = errors.Join(append(missing, err)...)
+		}
+	}
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	l.dbg.Load(m)
+	return m, nil
+}
+
+func (l *linker) pending(name string) *module {
+	name = path.Base(name)
+	for _, m := range l.loading {
+		if m.name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (l *linker) loadNeeded(name string) (Module, error) {
+	if path.IsAbs(name) {
+		return l.load(name)
+	}
+	for _, dir := range l.paths {
+		pathname := path.Join(dir, name)
+		if _, err := l.dbg.Stat(pathname); err != nil {
+			continue
+		}
+		return l.load(pathname)
+	}
+	return nil, debugger.ErrModuleNotFound
+}
+
+func (l *linker) resolve(m *module, sym *symbol) (uint64, error) {
+	if _, addr, err := l.dbg.FindSymbol(sym.name); err == nil {
+		return addr, nil
+	} else if addr, ok := l.lookupPending(sym.name); ok {
+		return addr, nil
+	} else if addr, ok := l.resolved[sym.name]; ok {
+		return addr, nil
+	} else if std_elf.ST_BIND(sym.info) == std_elf.STB_WEAK {
+		return 0, err
+	}
+	for _, callback := range l.unresolved {
+		addr, err := callback(m, sym.name)
+		if err == nil {
+			l.resolved[sym.name] = addr
+			return addr, nil
+		}
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (l *linker) lookupPending(name string) (uint64, bool) {
+	for _, m := range l.loading {
+		if addr, err := m.FindSymbol(name); err == nil {
+			return addr, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,230 @@
+package elf
+
+import (
+	std_elf "debug/elf"
+	"encoding/binary"
+	"math"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type module struct {
+	dbg         debugger.Debugger
+	name        string
+	class       std_elf.Class
+	machine     std_elf.Machine
+	order       binary.ByteOrder
+	region      emulator.MemRegion
+	bias        uint64
+	entry       uint64
+	progs       []std_elf.ProgHeader
+	relro       []std_elf.ProgHeader
+	image       []byte
+	dynamic     map[std_elf.DynTag][]uint64
+	needed      []string
+	symbols     []symbol
+	exports     map[string]uint64
+	relocs      []reloc
+	irelative   []uint64
+	initialized bool
+}
+
+func (m *module) mapImage(f *std_elf.File) error {
+	pageSize := m.dbg.Emulator().PageSize()
+	lo, hi := uint64(math.MaxUint64), uint64(0)
+	var dynamic *std_elf.Prog
+	for _, prog := range f.Progs {
+		switch prog.Type {
+		case std_elf.PT_LOAD:
+			lo = min(lo, prog.Vaddr)
+			hi = max(hi, prog.Vaddr+prog.Memsz)
+			m.progs = append(m.progs, prog.ProgHeader)
+		case std_elf.PT_DYNAMIC:
+			dynamic = prog
+		case std_elf.PT_GNU_RELRO:
+			m.relro = append(m.relro, prog.ProgHeader)
+		}
+	}
+	if len(m.progs) == 0 {
+		return ErrSegmentNotFound
+	}
+	lo &^= pageSize - 1
+	hi = debugger.Align(hi, pageSize)
+	var err error
+	if f.Type == std_elf.ET_EXEC {
+		m.region, err = m.dbg.MemMap(lo, hi-lo, emulator.MEM_PROT_ALL)
+	} else {
+		m.region, err = m.dbg.MapAlloc(hi-lo, emulator.MEM_PROT_ALL)
+	}
+	if err != nil {
+		return err
+	}
+	m.bias = m.region.Addr - lo
+	m.image = make([]byte, m.region.Size)
+	for _, prog := range f.Progs {
+		if prog.Type != std_elf.PT_LOAD {
+			continue
+		}
+		off := m.offset(prog.Vaddr)
+		_, err = prog.ReadAt(m.image[off:off+prog.Filesz], 0)
+		if err != nil {
+			m.Close()
+			return err
+		}
+	}
+	if f.Entry != 0 {
+		m.entry = m.bias + f.Entry
+	}
+	m.dynamic = make(map[std_elf.DynTag][]uint64)
+	if dynamic != nil {
+		size := m.wordSize() * 2
+		for data := m.image[m.offset(dynamic.Vaddr):][:dynamic.Memsz]; len(data) >= size; data = data[size:] {
+			tag := std_elf.DynTag(m.word(data))
+			if tag == std_elf.DT_NULL {
+				break
+			}
+			m.dynamic[tag] = append(m.dynamic[tag], m.word(data[size/2:]))
+		}
+	}
+	return nil
+}
+
+func (m *module) link(lookup func(*symbol) (uint64, error)) error {
+	err := m.relocate(lookup)
+	if err != nil {
+		return err
+	}
+	err = m.dbg.Emulator().MemWrite(m.region.Addr, m.image)
+	if err != nil {
+		return err
+	}
+	m.image = nil
+	return m.protect()
+}
+
+func (m *module) protect() error {
+	pageSize := m.dbg.Emulator().PageSize()
+	prots := make([]emulator.MemProt, m.region.Size/pageSize)
+	fill := func(vaddr, size uint64, fn func(*emulator.MemProt)) {
+		begin := m.offset(vaddr) / pageSize
+		end := debugger.Align(m.offset(vaddr)+size, pageSize) / pageSize
+		for i := begin; i < end && i < uint64(len(prots)); i++ {
+			fn(&prots[i])
+		}
+	}
+	for _, prog := range m.progs {
+		prot := toProt(prog.Flags)
+		fill(prog.Vaddr, prog.Memsz, func(p *emulator.MemProt) { *p |= prot })
+	}
+	for _, prog := range m.relro {
+		fill(prog.Vaddr, prog.Memsz, func(p *emulator.MemProt) { *p &^= emulator.MEM_PROT_WRITE })
+	}
+	for begin := 0; begin < len(prots); {
+		end := begin + 1
+		for end < len(prots) && prots[end] == prots[begin] {
+			end++
+		}
+		err := m.dbg.MemProtect(m.region.Addr+uint64(begin)*pageSize, uint64(end-begin)*pageSize, prots[begin])
+		if err != nil {
+			return err
+		}
+		begin = end
+	}
+	return nil
+}
+
+func (m *module) offset(vaddr uint64) uint64 {
+	return vaddr + m.bias - m.region.Addr
+}
+
+func (m *module) wordSize() int {
+	if m.class == std_elf.ELFCLASS64 {
+		return 8
+	}
+	return 4
+}
+
+func (m *module) word(b []byte) uint64 {
+	if m.class == std_elf.ELFCLASS64 {
+		return m.order.Uint64(b)
+	}
+	return uint64(m.order.Uint32(b))
+}
+
+func (m *module) putWord(b []byte, v uint64) {
+	if m.class == std_elf.ELFCLASS64 {
+		m.order.PutUint64(b, v)
+	} else {
+		m.order.PutUint32(b, uint32(v))
+	}
+}
+
+func (m *module) Close() error {
+	if m.region.Size == 0 {
+		return nil
+	}
+	err := m.dbg.MapFree(m.region.Addr, m.region.Size)
+	m.region = emulator.MemRegion{}
+	return err
+}
+
+func (m *module) Name() string {
+	return m.name
+}
+
+func (m *module) Region() (uint64, uint64) {
+	return m.region.Addr, m.region.Size
+}
+
+func (m *module) BaseAddr() uint64 {
+	return m.region.Addr
+}
+
+func (m *module) EntryAddr() uint64 {
+	return m.entry
+}
+
+func (m *module) FindSymbol(name string) (uint64, error) {
+	if addr, ok := m.exports[name]; ok {
+		return addr, nil
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) Symbols(yield func(debugger.Symbol) bool) {
+	for i := range m.symbols {
+		sym := &m.symbols[i]
+		if sym.name == "" || sym.shndx == std_elf.SHN_UNDEF {
+			continue
+		}
+		if !yield(debugger.Symbol{Name: sym.name, Value: m.symbolAddr(sym)}) {
+			return
+		}
+	}
+}
+
+func (m *module) Needed() []string {
+	return m.needed
+}
+
+func (m *module) lookup(sym *symbol) (uint64, error) {
+	if _, addr, err := m.dbg.FindSymbol(sym.name); err == nil {
+		return addr, nil
+	}
+	return m.FindSymbol(sym.name)
+}
+
+func toProt(flags std_elf.ProgFlag) emulator.MemProt {
+	var prot emulator.MemProt
+	if flags&std_elf.PF_R != 0 {
+		prot |= emulator.MEM_PROT_READ
+	}
+	if flags&std_elf.PF_W != 0 {
+		prot |= emulator.MEM_PROT_WRITE
+	}
+	if flags&std_elf.PF_X != 0 {
+		prot |= emulator.MEM_PROT_EXEC
+	}
+	return prot
+}
@@ -0,0 +1,263 @@
+package elf
+
+import (
+	std_elf "debug/elf"
+	"fmt"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+const (
+	aps2GroupedByInfo        = 1
+	aps2GroupedByOffsetDelta = 2
+	aps2GroupedByAddend      = 4
+	aps2GroupHasAddend       = 8
+)
+
+type reloc struct {
+	offset uint64
+	typ    uint32
+	sym    uint32
+	addend int64
+	rela   bool
+}
+
+func (m *module) parseRelocs() error {
+	m.parseTable(m.dynData(std_elf.DT_REL, std_elf.DT_RELSZ), false)
+	m.parseTable(m.dynData(std_elf.DT_RELA, std_elf.DT_RELASZ), true)
+	if err := m.parsePacked(m.dynData(DT_ANDROID_REL, DT_ANDROID_RELSZ), false); err != nil {
+		return err
+	}
+	if err := m.parsePacked(m.dynData(DT_ANDROID_RELA, DT_ANDROID_RELASZ), true); err != nil {
+		return err
+	}
+	m.parseRelr(m.dynData(DT_RELR, DT_RELRSZ))
+	pltrel, _ := m.dynValue(std_elf.DT_PLTREL)
+	m.parseTable(m.dynData(std_elf.DT_JMPREL, std_elf.DT_PLTRELSZ), std_elf.DynTag(pltrel) == std_elf.DT_RELA)
+	return nil
+}
+
+func (m *module) parseTable(data []byte, rela bool) {
+	ws := m.wordSize()
+	size := ws * 2
+	if rela {
+		size += ws
+	}
+	for ; len(data) >= size; data = data[size:] {
+		r := reloc{offset: m.word(data), rela: rela}
+		r.sym, r.typ = m.splitInfo(m.word(data[ws:]))
+		if rela {
+			if ws == 8 {
+				r.addend = int64(m.word(data[ws*2:]))
+			} else {
+				r.addend = int64(int32(m.word(data[ws*2:])))
+			}
+		}
+		m.relocs = append(m.relocs, r)
+	}
+}
+
+func (m *module) parsePacked(data []byte, rela bool) error {
+	if len(data) == 0 {
+		return nil
+	} else if len(data) < 4 || string(data[:4]) != "APS2" {
+		return ErrRelocInvalid
+	}
+	data = data[4:]
+	var exhausted bool
+	next := func() int64 {
+		v, n := sleb128(data)
+		if n == 0 {
+			exhausted = true
+		}
+		data = data[n:]
+		return v
+	}
+	count := next()
+	offset := uint64(next())
+	if exhausted || count < 0 || count > int64(len(m.image)/m.wordSize()) {
+		return ErrRelocInvalid
+	}
+	var info uint64
+	var addend int64
+	for count > 0 {
+		groupSize := next()
+		flags := next()
+		if exhausted || groupSize <= 0 || groupSize > count {
+			return ErrRelocInvalid
+		}
+		var delta uint64
+		if flags&aps2GroupedByOffsetDelta != 0 {
+			delta = uint64(next())
+		}
+		if flags&aps2GroupedByInfo != 0 {
+			info = uint64(next())
+		}
+		hasAddend := flags&aps2GroupHasAddend != 0
+		if !hasAddend {
+			addend = 0
+		} else if flags&aps2GroupedByAddend != 0 {
+			addend += next()
+		}
+		for i := int64(0); i < groupSize; i++ {
+			if flags&aps2GroupedByOffsetDelta != 0 {
+				offset += delta
+			} else {
+				offset += uint64(next())
+			}
+			if flags&aps2GroupedByInfo == 0 {
+				info = uint64(next())
+			}
+			if hasAddend && flags&aps2GroupedByAddend == 0 {
+				addend += next()
+			}
+			if exhausted {
+				return ErrRelocInvalid
+			}
+			r := reloc{offset: offset, addend: addend, rela: rela}
+			r.sym, r.typ = m.splitInfo(info)
+			m.relocs = append(m.relocs, r)
+		}
+		count -= groupSize
+	}
+	return nil
+}
+
+func (m *module) parseRelr(data []byte) {
+	ws := m.wordSize()
+	typ := uint32(std_elf.R_ARM_RELATIVE)
+	if m.machine == std_elf.EM_AARCH64 {
+		typ = uint32(std_elf.R_AARCH64_RELATIVE)
+	}
+	var base uint64
+	for ; len(data) >= ws; data = data[ws:] {
+		entry := m.word(data)
+		if entry&1 == 0 {
+			m.relocs = append(m.relocs, reloc{offset: entry, typ: typ})
+			base = entry + uint64(ws)
+			continue
+		}
+		for i := 0; entry != 0; i++ {
+			entry >>= 1
+			if entry&1 != 0 {
+				m.relocs = append(m.relocs, reloc{offset: base + uint64(i*ws), typ: typ})
+			}
+		}
+		base += uint64((ws*8 - 1) * ws)
+	}
+}
+
+func (m *module) splitInfo(info uint64) (sym, typ uint32) {
+	if m.class == std_elf.ELFCLASS64 {
+		return uint32(info >> 32), uint32(info)
+	}
+	return uint32(info >> 8), uint32(info & 0xff)
+}
+
+func (m *module) relocate(lookup func(*symbol) (uint64, error)) error {
+	ws := uint64(m.wordSize())
+	for _, r := range m.relocs {
+		off := m.offset(r.offset)
+		if off+ws > uint64(len(m.image)) {
+			return ErrRelocInvalid
+		}
+		b := m.image[off:]
+		addend := uint64(r.addend)
+		if !r.rela {
+			addend = m.word(b)
+		}
+		var sym uint64
+		var size uint64
+		if r.sym != 0 {
+			if int(r.sym) >= len(m.symbols) {
+				return ErrSymbolInvalid
+			}
+			s := &m.symbols[r.sym]
+			size = s.size
+			if m.isCopy(r.typ) {
+				if addr, err := lookup(s); err == nil && (addr < m.region.Addr || addr >= m.region.Addr+m.region.Size) {
+					sym = addr
+				}
+			} else if s.shndx != std_elf.SHN_UNDEF {
+				sym = m.symbolAddr(s)
+			} else if addr, err := lookup(s); err == nil {
+				sym = addr
+			} else if std_elf.ST_BIND(s.info) != std_elf.STB_WEAK {
+				return fmt.Errorf("%w: %s", debugger.ErrSymbolNotFound, s.name)
+			}
+		}
+		place := m.bias + r.offset
+		switch m.machine {
+		case std_elf.EM_ARM:
+			switch std_elf.R_ARM(r.typ) {
+			case std_elf.R_ARM_ABS32:
+				m.putWord(b, sym+addend)
+			case std_elf.R_ARM_REL32:
+				m.putWord(b, sym+addend-place)
+			case std_elf.R_ARM_GLOB_DAT, std_elf.R_ARM_JUMP_SLOT:
+				m.putWord(b, sym)
+			case std_elf.R_ARM_RELATIVE:
+				m.putWord(b, m.bias+addend)
+			case std_elf.R_ARM_IRELATIVE:
+				m.putWord(b, m.bias+addend)
+				m.irelative = append(m.irelative, place)
+			case std_elf.R_ARM_COPY:
+				if err := m.copyData(b, sym, size); err != nil {
+					return err
+				}
+			}
+		case std_elf.EM_AARCH64:
+			switch std_elf.R_AARCH64(r.typ) {
+			case std_elf.R_AARCH64_ABS64, std_elf.R_AARCH64_GLOB_DAT, std_elf.R_AARCH64_JUMP_SLOT:
+				m.putWord(b, sym+addend)
+			case std_elf.R_AARCH64_PREL64:
+				m.putWord(b, sym+addend-place)
+			case std_elf.R_AARCH64_RELATIVE:
+				m.putWord(b, m.bias+addend)
+			case std_elf.R_AARCH64_IRELATIVE:
+				m.putWord(b, m.bias+addend)
+				m.irelative = append(m.irelative, place)
+			case std_elf.R_AARCH64_COPY:
+				if err := m.copyData(b, sym, size); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *module) isCopy(typ uint32) bool {
+	if m.machine == std_elf.EM_AARCH64 {
+		return std_elf.R_AARCH64(typ) == std_elf.R_AARCH64_COPY
+	}
+	return std_elf.R_ARM(typ) == std_elf.R_ARM_COPY
+}
+
+func (m *module) copyData(b []byte, addr, size uint64) error {
+	if addr == 0 || size == 0 {
+		return nil
+	}
+	data, err := m.dbg.Emulator().MemRead(addr, size)
+	if err != nil {
+		return err
+	}
+	copy(b, data)
+	return nil
+}
+
+func sleb128(b []byte) (int64, int) {
+	var result int64
+	var shift uint
+	for i, c := range b {
+		result |= int64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				result |= -1 << shift
+			}
+			return result, i + 1
+		}
+	}
+	return result, len(b)
+}
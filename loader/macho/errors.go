@@ -0,0 +1,11 @@
+package macho
+
+import "errors"
+
+var (
+	ErrSegmentNotFound    = errors.New("load segment not found")
+	ErrLoadCommandInvalid = errors.New("load command invalid")
+	ErrFixupInvalid       = errors.New("fixup invalid")
+	ErrFixupUnsupported   = errors.New("fixup unsupported")
+	ErrExportInvalid      = errors.New("export trie invalid")
+)
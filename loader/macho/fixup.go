@@ -0,0 +1,514 @@
+package macho
+
+import (
+	"bytes"
+	"fmt"
+)
+
+const (
+	rebaseOpcodeMask                    = 0xf0
+	rebaseImmediateMask                 = 0x0f
+	rebaseOpcodeDone                    = 0x00
+	rebaseOpcodeSetTypeImm              = 0x10
+	rebaseOpcodeSetSegmentAndOffsetUleb = 0x20
+	rebaseOpcodeAddAddrUleb             = 0x30
+	rebaseOpcodeAddAddrImmScaled        = 0x40
+	rebaseOpcodeDoRebaseImmTimes        = 0x50
+	rebaseOpcodeDoRebaseUlebTimes       = 0x60
+	rebaseOpcodeDoRebaseAddAddrUleb     = 0x70
+	rebaseOpcodeDoRebaseUlebTimesSkip   = 0x80
+
+	bindOpcodeMask                    = 0xf0
+	bindImmediateMask                 = 0x0f
+	bindOpcodeDone                    = 0x00
+	bindOpcodeSetDylibOrdinalImm      = 0x10
+	bindOpcodeSetDylibOrdinalUleb     = 0x20
+	bindOpcodeSetDylibSpecialImm      = 0x30
+	bindOpcodeSetSymbolFlagsImm       = 0x40
+	bindOpcodeSetTypeImm              = 0x50
+	bindOpcodeSetAddendSleb           = 0x60
+	bindOpcodeSetSegmentAndOffsetUleb = 0x70
+	bindOpcodeAddAddrUleb             = 0x80
+	bindOpcodeDoBind                  = 0x90
+	bindOpcodeDoBindAddAddrUleb       = 0xa0
+	bindOpcodeDoBindAddAddrImmScaled  = 0xb0
+	bindOpcodeDoBindUlebTimesSkip     = 0xc0
+
+	bindSpecialDylibSelf       = 0
+	bindSpecialDylibFlatLookup = -2
+	bindSymbolFlagsWeakImport  = 0x01
+
+	chainedPtrArm64e           = 1
+	chainedPtr64               = 2
+	chainedPtr64Offset         = 6
+	chainedPtrArm64eUserland   = 9
+	chainedPtrArm64eUserland24 = 12
+	chainedPtrStartNone        = 0xffff
+	chainedPtrStartMulti       = 0x8000
+
+	chainedImport         = 1
+	chainedImportAddend   = 2
+	chainedImportAddend64 = 3
+	chainedImportWeak     = 1 << 8
+	chainedImport64Weak   = 1 << 16
+
+	exportSymbolFlagsKindMask     = 0x03
+	exportSymbolFlagsKindAbsolute = 0x02
+	exportSymbolFlagsReexport     = 0x08
+
+	pointerSize = 8
+)
+
+type opReader struct {
+	data []byte
+	err  error
+}
+
+func (r *opReader) eof() bool {
+	return r.err != nil || len(r.data) == 0
+}
+
+func (r *opReader) byte() byte {
+	if len(r.data) == 0 {
+		r.err = ErrFixupInvalid
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *opReader) uleb() uint64 {
+	var result uint64
+	var shift uint
+	for i, c := range r.data {
+		if shift < 64 {
+			result |= uint64(c&0x7f) << shift
+		}
+		shift += 7
+		if c&0x80 == 0 {
+			r.data = r.data[i+1:]
+			return result
+		}
+	}
+	r.err = ErrFixupInvalid
+	return 0
+}
+
+func (r *opReader) sleb() int64 {
+	var result int64
+	var shift uint
+	for i, c := range r.data {
+		if shift < 64 {
+			result |= int64(c&0x7f) << shift
+		}
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				result |= -1 << shift
+			}
+			r.data = r.data[i+1:]
+			return result
+		}
+	}
+	r.err = ErrFixupInvalid
+	return 0
+}
+
+func (r *opReader) cstring() string {
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = ErrFixupInvalid
+		return ""
+	}
+	s := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return s
+}
+
+func (m *module) fixup(resolve func(ordinal int, name string) (uint64, error)) error {
+	err := m.rebase(m.rebaseInfo)
+	if err != nil {
+		return err
+	}
+	err = m.bind(m.bindInfo, false, resolve)
+	if err != nil {
+		return err
+	}
+	err = m.bind(m.lazyInfo, true, resolve)
+	if err != nil {
+		return err
+	}
+	return m.chainedFixups(m.chained, resolve)
+}
+
+func (m *module) rebase(info []byte) error {
+	r := opReader{data: info}
+	var seg, off uint64
+	for !r.eof() {
+		b := r.byte()
+		op, imm := b&rebaseOpcodeMask, uint64(b&rebaseImmediateMask)
+		var err error
+		switch op {
+		case rebaseOpcodeDone:
+			return nil
+		case rebaseOpcodeSetTypeImm:
+		case rebaseOpcodeSetSegmentAndOffsetUleb:
+			seg, off = imm, r.uleb()
+		case rebaseOpcodeAddAddrUleb:
+			off += r.uleb()
+		case rebaseOpcodeAddAddrImmScaled:
+			off += imm * pointerSize
+		case rebaseOpcodeDoRebaseImmTimes:
+			for i := uint64(0); i < imm && err == nil; i++ {
+				err = m.rebaseAt(seg, off)
+				off += pointerSize
+			}
+		case rebaseOpcodeDoRebaseUlebTimes:
+			count := r.uleb()
+			for i := uint64(0); i < count && err == nil; i++ {
+				err = m.rebaseAt(seg, off)
+				off += pointerSize
+			}
+		case rebaseOpcodeDoRebaseAddAddrUleb:
+			err = m.rebaseAt(seg, off)
+			off += r.uleb() + pointerSize
+		case rebaseOpcodeDoRebaseUlebTimesSkip:
+			count, skip := r.uleb(), r.uleb()
+			for i := uint64(0); i < count && err == nil; i++ {
+				err = m.rebaseAt(seg, off)
+				off += skip + pointerSize
+			}
+		default:
+			return ErrFixupInvalid
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+
+func (m *module) bind(info []byte, lazy bool, resolve func(ordinal int, name string) (uint64, error)) error {
+	r := opReader{data: info}
+	var seg, off uint64
+	var ordinal int
+	var name string
+	var addend int64
+	var weak bool
+	for !r.eof() {
+		b := r.byte()
+		op, imm := b&bindOpcodeMask, uint64(b&bindImmediateMask)
+		var err error
+		switch op {
+		case bindOpcodeDone:
+			if !lazy {
+				return nil
+			}
+		case bindOpcodeSetDylibOrdinalImm:
+			ordinal = int(imm)
+		case bindOpcodeSetDylibOrdinalUleb:
+			ordinal = int(r.uleb())
+		case bindOpcodeSetDylibSpecialImm:
+			if imm == 0 {
+				ordinal = bindSpecialDylibSelf
+			} else {
+				ordinal = int(int8(b | bindOpcodeMask))
+			}
+		case bindOpcodeSetSymbolFlagsImm:
+			name = r.cstring()
+			weak = imm&bindSymbolFlagsWeakImport != 0
+		case bindOpcodeSetTypeImm:
+		case bindOpcodeSetAddendSleb:
+			addend = r.sleb()
+		case bindOpcodeSetSegmentAndOffsetUleb:
+			seg, off = imm, r.uleb()
+		case bindOpcodeAddAddrUleb:
+			off += r.uleb()
+		case bindOpcodeDoBind:
+			err = m.bindAt(seg, off, ordinal, name, addend, weak, lazy, resolve)
+			off += pointerSize
+		case bindOpcodeDoBindAddAddrUleb:
+			err = m.bindAt(seg, off, ordinal, name, addend, weak, lazy, resolve)
+			off += r.uleb() + pointerSize
+		case bindOpcodeDoBindAddAddrImmScaled:
+			err = m.bindAt(seg, off, ordinal, name, addend, weak, lazy, resolve)
+			off += imm*pointerSize + pointerSize
+		case bindOpcodeDoBindUlebTimesSkip:
+			count, skip := r.uleb(), r.uleb()
+			for i := uint64(0); i < count && err == nil; i++ {
+				err = m.bindAt(seg, off, ordinal, name, addend, weak, lazy, resolve)
+				off += skip + pointerSize
+			}
+		default:
+			return ErrFixupUnsupported
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return r.err
+}
+
+func (m *module) segmentOffset(seg, off uint64) (uint64, error) {
+	if seg >= uint64(len(m.segments)) || off+pointerSize > m.segments[seg].Memsz {
+		return 0, ErrFixupInvalid
+	}
+	return m.offset(m.segments[seg].Addr + off), nil
+}
+
+func (m *module) rebaseAt(seg, off uint64) error {
+	i, err := m.segmentOffset(seg, off)
+	if err != nil {
+		return err
+	}
+	m.order.PutUint64(m.image[i:], m.order.Uint64(m.image[i:])+m.slide)
+	return nil
+}
+
+func (m *module) bindAt(seg, off uint64, ordinal int, name string, addend int64, weak, lazy bool, resolve func(ordinal int, name string) (uint64, error)) error {
+	i, err := m.segmentOffset(seg, off)
+	if err != nil {
+		return err
+	}
+	if addr, err := resolve(ordinal, name); err == nil {
+		m.order.PutUint64(m.image[i:], addr+uint64(addend))
+	} else if weak {
+		m.order.PutUint64(m.image[i:], 0)
+	} else if !lazy {
+		return fmt.Errorf("%w: %s", err, name)
+	}
+	return nil
+}
+
+func (m *module) chainedFixups(data []byte, resolve func(ordinal int, name string) (uint64, error)) error {
+	if len(data) == 0 {
+		return nil
+	} else if len(data) < 28 {
+		return ErrFixupInvalid
+	}
+	startsOff := uint64(m.order.Uint32(data[4:]))
+	importsOff := uint64(m.order.Uint32(data[8:]))
+	symbolsOff := uint64(m.order.Uint32(data[12:]))
+	count := uint64(m.order.Uint32(data[16:]))
+	format := m.order.Uint32(data[20:])
+	if m.order.Uint32(data[24:]) != 0 {
+		return ErrFixupUnsupported
+	}
+	imports, err := m.chainedImports(data, importsOff, symbolsOff, count, format, resolve)
+	if err != nil {
+		return err
+	}
+	if startsOff+4 > uint64(len(data)) {
+		return ErrFixupInvalid
+	}
+	starts := data[startsOff:]
+	segCount := uint64(m.order.Uint32(starts))
+	if 4+segCount*4 > uint64(len(starts)) {
+		return ErrFixupInvalid
+	}
+	for i := range segCount {
+		off := uint64(m.order.Uint32(starts[4+i*4:]))
+		if off == 0 {
+			continue
+		}
+		if off+22 > uint64(len(starts)) {
+			return ErrFixupInvalid
+		}
+		err = m.chainedSegment(starts[off:], imports)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *module) chainedImports(data []byte, importsOff, symbolsOff, count uint64, format uint32, resolve func(ordinal int, name string) (uint64, error)) ([]uint64, error) {
+	var size uint64
+	switch format {
+	case chainedImport:
+		size = 4
+	case chainedImportAddend:
+		size = 8
+	case chainedImportAddend64:
+		size = 16
+	default:
+		return nil, ErrFixupUnsupported
+	}
+	if importsOff+count*size > uint64(len(data)) || symbolsOff > uint64(len(data)) {
+		return nil, ErrFixupInvalid
+	}
+	symbols := data[symbolsOff:]
+	imports := make([]uint64, count)
+	for i := range count {
+		entry := data[importsOff+i*size:]
+		var ordinal int
+		var nameOff uint64
+		var addend int64
+		var weak bool
+		if format == chainedImportAddend64 {
+			v := m.order.Uint64(entry)
+			ordinal = int(uint16(v))
+			if ordinal > 0xfff0 {
+				ordinal = int(int16(v))
+			}
+			weak = v&chainedImport64Weak != 0
+			nameOff = v >> 32
+			addend = int64(m.order.Uint64(entry[8:]))
+		} else {
+			v := m.order.Uint32(entry)
+			ordinal = int(uint8(v))
+			if ordinal > 0xf0 {
+				ordinal = int(int8(v))
+			}
+			weak = v&chainedImportWeak != 0
+			nameOff = uint64(v >> 9)
+			if format == chainedImportAddend {
+				addend = int64(int32(m.order.Uint32(entry[4:])))
+			}
+		}
+		if nameOff >= uint64(len(symbols)) {
+			return nil, ErrFixupInvalid
+		}
+		name, _, _ := bytes.Cut(symbols[nameOff:], []byte{0})
+		if addr, err := resolve(ordinal, string(name)); err == nil {
+			imports[i] = addr + uint64(addend)
+		} else if !weak {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+	}
+	return imports, nil
+}
+
+func (m *module) chainedSegment(seg []byte, imports []uint64) error {
+	pageSize := uint64(m.order.Uint16(seg[4:]))
+	format := m.order.Uint16(seg[6:])
+	segOff := m.order.Uint64(seg[8:])
+	pageCount := uint64(m.order.Uint16(seg[20:]))
+	if 22+pageCount*2 > uint64(len(seg)) {
+		return ErrFixupInvalid
+	}
+	base := m.header - m.region.Addr
+	for i := range pageCount {
+		start := m.order.Uint16(seg[22+i*2:])
+		if start == chainedPtrStartNone {
+			continue
+		} else if start&chainedPtrStartMulti != 0 {
+			return ErrFixupUnsupported
+		}
+		err := m.chainedPage(base+segOff+i*pageSize+uint64(start), format, imports)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *module) chainedPage(off uint64, format uint16, imports []uint64) error {
+	for {
+		if off+8 > uint64(len(m.image)) {
+			return ErrFixupInvalid
+		}
+		raw := m.order.Uint64(m.image[off:])
+		var value, next, stride uint64
+		switch format {
+		case chainedPtr64, chainedPtr64Offset:
+			next, stride = raw>>51&0xfff, 4
+			if raw>>63 != 0 {
+				ordinal := raw & 0xffffff
+				if ordinal >= uint64(len(imports)) {
+					return ErrFixupInvalid
+				}
+				value = imports[ordinal] + raw>>24&0xff
+			} else {
+				target := raw & (1<<36 - 1)
+				if format == chainedPtr64 {
+					target += m.slide
+				} else {
+					target += m.header
+				}
+				value = target | (raw>>36&0xff)<<56
+			}
+		case chainedPtrArm64e, chainedPtrArm64eUserland, chainedPtrArm64eUserland24:
+			next, stride = raw>>51&0x7ff, 8
+			auth := raw>>63 != 0
+			switch {
+			case raw>>62&1 != 0:
+				ordinal := raw & 0xffff
+				if format == chainedPtrArm64eUserland24 {
+					ordinal = raw & 0xffffff
+				}
+				if ordinal >= uint64(len(imports)) {
+					return ErrFixupInvalid
+				}
+				value = imports[ordinal]
+				if !auth {
+					value += uint64(int64(raw<<13) >> 45)
+				}
+			case auth:
+				value = m.header + raw&0xffffffff
+			default:
+				target := raw & (1<<43 - 1)
+				if format == chainedPtrArm64e {
+					target += m.slide
+				} else {
+					target += m.header
+				}
+				value = target | (raw>>43&0xff)<<56
+			}
+		default:
+			return ErrFixupUnsupported
+		}
+		m.order.PutUint64(m.image[off:], value)
+		if next == 0 {
+			return nil
+		}
+		off += next * stride
+	}
+}
+
+func (m *module) parseExports(trie []byte) error {
+	type node struct {
+		off    uint64
+		prefix string
+	}
+	visited := make(map[uint64]struct{})
+	stack := []node{{0, ""}}
+	for len(stack) > 0 && len(trie) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := visited[n.off]; ok || n.off >= uint64(len(trie)) {
+			return ErrExportInvalid
+		}
+		visited[n.off] = struct{}{}
+		r := opReader{data: trie[n.off:]}
+		if size := r.uleb(); size != 0 {
+			if size > uint64(len(r.data)) {
+				return ErrExportInvalid
+			}
+			info := opReader{data: r.data[:size]}
+			r.data = r.data[size:]
+			flags := info.uleb()
+			if flags&exportSymbolFlagsReexport == 0 {
+				addr := info.uleb()
+				if flags&exportSymbolFlagsKindMask != exportSymbolFlagsKindAbsolute {
+					addr += m.header
+				}
+				m.exports[n.prefix] = addr
+			}
+			if info.err != nil {
+				return ErrExportInvalid
+			}
+		}
+		children := r.byte()
+		for range children {
+			edge := r.cstring()
+			child := r.uleb()
+			stack = append(stack, node{child, n.prefix + edge})
+		}
+		if r.err != nil {
+			return ErrExportInvalid
+		}
+	}
+	return nil
+}
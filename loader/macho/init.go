@@ -0,0 +1,63 @@
+package macho
+
+import (
+	"context"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+func (m *module) Init(ctx context.Context) error {
+	if m.initialized {
+		return nil
+	}
+	m.initialized = true
+	for _, sect := range m.inits {
+		data, err := m.dbg.Emulator().MemRead(sect.addr, sect.size)
+		if err != nil {
+			return err
+		}
+		size := uint64(pointerSize)
+		if sect.offsets {
+			size = 4
+		}
+		for i := uint64(0); i+size <= sect.size; i += size {
+			var fn uint64
+			if sect.offsets {
+				fn = m.header + uint64(m.order.Uint32(data[i:]))
+			} else {
+				fn = m.order.Uint64(data[i:])
+			}
+			if fn == 0 {
+				continue
+			}
+			err = m.callInit(ctx, sect.name, int(i/size), fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *module) callInit(ctx context.Context, name string, index int, addr uint64) error {
+	err := ctx.Err()
+	if err != nil {
+		return context.Cause(ctx)
+	}
+	task, err := m.dbg.CreateTask(ctx)
+	if err != nil {
+		return err
+	}
+	defer task.Close()
+	err = m.dbg.CallTaskOf(task, addr)
+	if err != nil {
+		return err
+	}
+	err = task.SyncRun()
+	if err == nil {
+		return nil
+	} else if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return debugger.NewInitException(task.Context(), name, index, err)
+}
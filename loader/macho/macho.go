@@ -0,0 +1,87 @@
+package macho
+
+import (
+	"bytes"
+	std_macho "debug/macho"
+	"io/fs"
+	"path"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type Module interface {
+	debugger.Module
+	debugger.SymbolIter
+	Needed() []string
+}
+
+type UnresolvedCallback = func(module Module, name string) (uint64, error)
+
+func Load(dbg debugger.Debugger, fsys filesystem.FS, name string, unresolved ...UnresolvedCallback) (Module, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err = thin(data)
+	if err != nil {
+		return nil, err
+	}
+	f, err := std_macho.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var arch emulator.Arch
+	switch f.Cpu {
+	case std_macho.CpuArm64:
+		arch = emulator.ARCH_ARM64
+	default:
+		return nil, emulator.ErrArchUnsupported
+	}
+	if arch != dbg.Arch() {
+		return nil, emulator.ErrArchMismatch
+	}
+	m := &module{
+		dbg:     dbg,
+		name:    path.Base(name),
+		order:   f.ByteOrder,
+		data:    data,
+		exports: make(map[string]uint64),
+	}
+	err = m.mapImage(f)
+	if err != nil {
+		return nil, err
+	}
+	err = m.parseLoads(f)
+	if err == nil {
+		err = m.link(func(ordinal int, name string) (uint64, error) {
+			return m.resolve(ordinal, name, unresolved)
+		})
+	}
+	m.data = nil
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func thin(data []byte) ([]byte, error) {
+	ff, err := std_macho.NewFatFile(bytes.NewReader(data))
+	if err == std_macho.ErrNotFat {
+		return data, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, arch := range ff.Arches {
+		if arch.Cpu != std_macho.CpuArm64 {
+			continue
+		}
+		if uint64(arch.Offset)+uint64(arch.Size) > uint64(len(data)) {
+			return nil, ErrLoadCommandInvalid
+		}
+		return data[arch.Offset:][:arch.Size], nil
+	}
+	return nil, emulator.ErrArchUnsupported
+}
@@ -0,0 +1,342 @@
+package macho
+
+import (
+	"bytes"
+	std_macho "debug/macho"
+	"encoding/binary"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	LoadCmdDyldInfo       std_macho.LoadCmd = 0x22
+	LoadCmdDyldInfoOnly   std_macho.LoadCmd = 0x80000022
+	LoadCmdLazyLoadDylib  std_macho.LoadCmd = 0x20
+	LoadCmdLoadWeakDylib  std_macho.LoadCmd = 0x80000018
+	LoadCmdReexportDylib  std_macho.LoadCmd = 0x8000001f
+	LoadCmdLoadUpwDylib   std_macho.LoadCmd = 0x80000023
+	LoadCmdMain           std_macho.LoadCmd = 0x80000028
+	LoadCmdExportsTrie    std_macho.LoadCmd = 0x80000033
+	LoadCmdChainedFixups  std_macho.LoadCmd = 0x80000034
+	sectionTypeMask       uint32            = 0xff
+	sectionModInitPointer uint32            = 0x09
+	sectionInitOffsets    uint32            = 0x16
+	symbolTypeStab        uint8             = 0xe0
+	symbolTypeMask        uint8             = 0x0e
+	symbolTypeSect        uint8             = 0x0e
+	symbolTypeExt         uint8             = 0x01
+)
+
+type initSection struct {
+	name    string
+	addr    uint64
+	size    uint64
+	offsets bool
+}
+
+type module struct {
+	dbg         debugger.Debugger
+	name        string
+	order       binary.ByteOrder
+	region      emulator.MemRegion
+	slide       uint64
+	header      uint64
+	entry       uint64
+	segments    []std_macho.SegmentHeader
+	data        []byte
+	image       []byte
+	needed      []string
+	rebaseInfo  []byte
+	bindInfo    []byte
+	lazyInfo    []byte
+	chained     []byte
+	symbols     []debugger.Symbol
+	exports     map[string]uint64
+	inits       []initSection
+	initialized bool
+}
+
+func (m *module) mapImage(f *std_macho.File) error {
+	pageSize := m.dbg.Emulator().PageSize()
+	lo, hi := uint64(math.MaxUint64), uint64(0)
+	header, found := uint64(0), false
+	for _, load := range f.Loads {
+		seg, ok := load.(*std_macho.Segment)
+		if !ok {
+			continue
+		}
+		m.segments = append(m.segments, seg.SegmentHeader)
+		if seg.Prot == 0 || seg.Memsz == 0 {
+			continue
+		}
+		lo = min(lo, seg.Addr)
+		hi = max(hi, seg.Addr+seg.Memsz)
+		if seg.Offset == 0 && seg.Filesz != 0 && !found {
+			header, found = seg.Addr, true
+		}
+	}
+	if hi == 0 {
+		return ErrSegmentNotFound
+	}
+	lo &^= pageSize - 1
+	hi = debugger.Align(hi, pageSize)
+	var err error
+	if f.Type == std_macho.TypeExec && f.Flags&std_macho.FlagPIE == 0 {
+		m.region, err = m.dbg.MemMap(lo, hi-lo, emulator.MEM_PROT_ALL)
+	} else {
+		m.region, err = m.dbg.MapAlloc(hi-lo, emulator.MEM_PROT_ALL)
+	}
+	if err != nil {
+		return err
+	}
+	m.slide = m.region.Addr - lo
+	if found {
+		m.header = m.slide + header
+	} else {
+		m.header = m.region.Addr
+	}
+	m.image = make([]byte, m.region.Size)
+	for _, seg := range m.segments {
+		if seg.Prot == 0 || seg.Filesz == 0 {
+			continue
+		}
+		if seg.Filesz > seg.Memsz || seg.Offset+seg.Filesz > uint64(len(m.data)) {
+			m.Close()
+			return ErrSegmentNotFound
+		}
+		off := m.offset(seg.Addr)
+		copy(m.image[off:off+seg.Filesz], m.data[seg.Offset:])
+	}
+	return nil
+}
+
+func (m *module) parseLoads(f *std_macho.File) error {
+	for _, load := range f.Loads {
+		raw := load.Raw()
+		if len(raw) < 8 {
+			return ErrLoadCommandInvalid
+		}
+		var err error
+		switch cmd := std_macho.LoadCmd(m.order.Uint32(raw)); cmd {
+		case std_macho.LoadCmdDylib, LoadCmdLoadWeakDylib, LoadCmdReexportDylib, LoadCmdLazyLoadDylib, LoadCmdLoadUpwDylib:
+			if len(raw) < 12 {
+				return ErrLoadCommandInvalid
+			}
+			off := m.order.Uint32(raw[8:])
+			if uint64(off) >= uint64(len(raw)) {
+				return ErrLoadCommandInvalid
+			}
+			name, _, _ := bytes.Cut(raw[off:], []byte{0})
+			m.needed = append(m.needed, string(name))
+		case LoadCmdDyldInfo, LoadCmdDyldInfoOnly:
+			if len(raw) < 48 {
+				return ErrLoadCommandInvalid
+			}
+			m.rebaseInfo, err = m.linkedit(raw[8:])
+			if err == nil {
+				m.bindInfo, err = m.linkedit(raw[16:])
+			}
+			if err == nil {
+				m.lazyInfo, err = m.linkedit(raw[32:])
+			}
+			if err == nil {
+				var trie []byte
+				trie, err = m.linkedit(raw[40:])
+				if err == nil {
+					err = m.parseExports(trie)
+				}
+			}
+		case LoadCmdExportsTrie:
+			var trie []byte
+			trie, err = m.linkedit(raw[8:])
+			if err == nil {
+				err = m.parseExports(trie)
+			}
+		case LoadCmdChainedFixups:
+			m.chained, err = m.linkedit(raw[8:])
+		case LoadCmdMain:
+			if len(raw) < 16 {
+				return ErrLoadCommandInvalid
+			}
+			m.entry = m.header + m.order.Uint64(raw[8:])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, sect := range f.Sections {
+		switch sect.Flags & sectionTypeMask {
+		case sectionModInitPointer:
+			m.inits = append(m.inits, initSection{sect.Name, m.slide + sect.Addr, sect.Size, false})
+		case sectionInitOffsets:
+			m.inits = append(m.inits, initSection{sect.Name, m.slide + sect.Addr, sect.Size, true})
+		}
+	}
+	m.parseSymtab(f)
+	return nil
+}
+
+func (m *module) linkedit(raw []byte) ([]byte, error) {
+	off, size := uint64(m.order.Uint32(raw)), uint64(m.order.Uint32(raw[4:]))
+	if off+size > uint64(len(m.data)) {
+		return nil, ErrLoadCommandInvalid
+	}
+	return m.data[off:][:size], nil
+}
+
+func (m *module) parseSymtab(f *std_macho.File) {
+	if f.Symtab == nil {
+		return
+	}
+	export := len(m.exports) == 0
+	for _, sym := range f.Symtab.Syms {
+		if sym.Type&symbolTypeStab != 0 || sym.Type&symbolTypeMask != symbolTypeSect || sym.Name == "" {
+			continue
+		}
+		addr := m.slide + sym.Value
+		m.symbols = append(m.symbols, debugger.Symbol{Name: sym.Name, Value: addr})
+		if export && sym.Type&symbolTypeExt != 0 {
+			m.exports[sym.Name] = addr
+		}
+	}
+}
+
+func (m *module) link(resolve func(ordinal int, name string) (uint64, error)) error {
+	err := m.fixup(resolve)
+	if err != nil {
+		return err
+	}
+	err = m.dbg.Emulator().MemWrite(m.region.Addr, m.image)
+	if err != nil {
+		return err
+	}
+	m.image = nil
+	return m.protect()
+}
+
+func (m *module) protect() error {
+	pageSize := m.dbg.Emulator().PageSize()
+	prots := make([]emulator.MemProt, m.region.Size/pageSize)
+	for _, seg := range m.segments {
+		if seg.Prot == 0 || seg.Memsz == 0 {
+			continue
+		}
+		prot := toProt(seg.Prot)
+		begin := m.offset(seg.Addr) / pageSize
+		end := debugger.Align(m.offset(seg.Addr)+seg.Memsz, pageSize) / pageSize
+		for i := begin; i < end && i < uint64(len(prots)); i++ {
+			prots[i] |= prot
+		}
+	}
+	for begin := 0; begin < len(prots); {
+		end := begin + 1
+		for end < len(prots) && prots[end] == prots[begin] {
+			end++
+		}
+		err := m.dbg.MemProtect(m.region.Addr+uint64(begin)*pageSize, uint64(end-begin)*pageSize, prots[begin])
+		if err != nil {
+			return err
+		}
+		begin = end
+	}
+	return nil
+}
+
+func (m *module) resolve(ordinal int, name string, unresolved []UnresolvedCallback) (uint64, error) {
+	if ordinal == bindSpecialDylibSelf || ordinal == bindSpecialDylibFlatLookup {
+		if addr, ok := m.exports[name]; ok {
+			return addr, nil
+		}
+	}
+	if _, addr, err := m.dbg.FindSymbol(name); err == nil {
+		return addr, nil
+	} else if name, ok := strings.CutPrefix(name, "_"); ok {
+		if _, addr, err := m.dbg.FindSymbol(name); err == nil {
+			return addr, nil
+		}
+	}
+	for _, callback := range unresolved {
+		addr, err := callback(m, name)
+		if err == nil {
+			return addr, nil
+		}
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) offset(vaddr uint64) uint64 {
+	return vaddr + m.slide - m.region.Addr
+}
+
+func (m *module) Close() error {
+	if m.region.Size == 0 {
+		return nil
+	}
+	err := m.dbg.MapFree(m.region.Addr, m.region.Size)
+	m.region = emulator.MemRegion{}
+	return err
+}
+
+func (m *module) Name() string {
+	return m.name
+}
+
+func (m *module) Region() (uint64, uint64) {
+	return m.region.Addr, m.region.Size
+}
+
+func (m *module) BaseAddr() uint64 {
+	return m.header
+}
+
+func (m *module) EntryAddr() uint64 {
+	return m.entry
+}
+
+func (m *module) FindSymbol(name string) (uint64, error) {
+	if addr, ok := m.exports[name]; ok {
+		return addr, nil
+	} else if addr, ok = m.exports["_"+name]; ok {
+		return addr, nil
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) Symbols(yield func(debugger.Symbol) bool) {
+	if len(m.symbols) != 0 {
+		for _, sym := range m.symbols {
+			if !yield(sym) {
+				return
+			}
+		}
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.exports)) {
+		if !yield(debugger.Symbol{Name: name, Value: m.exports[name]}) {
+			return
+		}
+	}
+}
+
+func (m *module) Needed() []string {
+	return m.needed
+}
+
+func toProt(prot uint32) emulator.MemProt {
+	var p emulator.MemProt
+	if prot&0x1 != 0 {
+		p |= emulator.MEM_PROT_READ
+	}
+	if prot&0x2 != 0 {
+		p |= emulator.MEM_PROT_WRITE
+	}
+	if prot&0x4 != 0 {
+		p |= emulator.MEM_PROT_EXEC
+	}
+	return p
+}
@@ -0,0 +1,11 @@
+package pe
+
+import "errors"
+
+var (
+	ErrSectionInvalid = errors.New("section invalid")
+	ErrRelocInvalid   = errors.New("base relocation invalid")
+	ErrImportInvalid  = errors.New("import directory invalid")
+	ErrExportInvalid  = errors.New("export directory invalid")
+	ErrInitFailed     = errors.New("dll initialization failed")
+)
@@ -0,0 +1,99 @@
+package pe
+
+import (
+	"context"
+	std_pe "debug/pe"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+const DLL_PROCESS_ATTACH = 1
+
+func (m *module) Init(ctx context.Context) error {
+	if m.initialized {
+		return nil
+	}
+	m.initialized = true
+	callbacks, err := m.tlsCallbacks()
+	if err != nil {
+		return err
+	}
+	for i, callback := range callbacks {
+		_, err = m.callInit(ctx, "tls", i, callback)
+		if err != nil {
+			return err
+		}
+	}
+	if !m.dll || m.entry == 0 {
+		return nil
+	}
+	ok, err := m.callInit(ctx, "DllMain", 0, m.entry)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrInitFailed
+	}
+	return nil
+}
+
+func (m *module) tlsCallbacks() ([]uint64, error) {
+	if len(m.dirs) <= std_pe.IMAGE_DIRECTORY_ENTRY_TLS {
+		return nil, nil
+	}
+	dir := m.dirs[std_pe.IMAGE_DIRECTORY_ENTRY_TLS]
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return nil, nil
+	}
+	ws := uint64(m.wordSize())
+	data, err := m.dbg.Emulator().MemRead(m.region.Addr+uint64(dir.VirtualAddress), ws*4)
+	if err != nil {
+		return nil, err
+	}
+	var callbacks []uint64
+	for addr := m.word(data[ws*3:]); addr != 0; addr += ws {
+		data, err = m.dbg.Emulator().MemRead(addr, ws)
+		if err != nil {
+			return nil, err
+		}
+		callback := m.word(data)
+		if callback == 0 {
+			break
+		}
+		callbacks = append(callbacks, callback)
+	}
+	return callbacks, nil
+}
+
+func (m *module) callInit(ctx context.Context, name string, index int, addr uint64) (bool, error) {
+	err := ctx.Err()
+	if err != nil {
+		return false, context.Cause(ctx)
+	}
+	task, err := m.dbg.CreateTask(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer task.Close()
+	var calling debugger.Calling = debugger.Calling_Win64
+	if !m.pe64 {
+		calling = debugger.Calling_Stdcall
+	}
+	err = task.Context().ArgWrite(calling, uintptr(m.region.Addr), uint32(DLL_PROCESS_ATTACH), uintptr(0))
+	if err != nil {
+		return false, err
+	}
+	err = m.dbg.CallTaskOf(task, addr)
+	if err != nil {
+		return false, err
+	}
+	err = task.SyncRun()
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, context.Cause(ctx)
+		}
+		return false, debugger.NewInitException(task.Context(), name, index, err)
+	}
+	var ret uint32
+	err = task.Context().RetExtractOf(calling, &ret)
+	return ret != 0, err
+}
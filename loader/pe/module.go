@@ -0,0 +1,279 @@
+package pe
+
+import (
+	"bytes"
+	std_pe "debug/pe"
+	"encoding/binary"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+type module struct {
+	dbg         debugger.Debugger
+	name        string
+	pe64        bool
+	dll         bool
+	region      emulator.MemRegion
+	imageBase   uint64
+	delta       uint64
+	entry       uint64
+	headers     uint64
+	sections    []std_pe.SectionHeader
+	dirs        []std_pe.DataDirectory
+	image       []byte
+	needed      []string
+	names       map[string]uint16
+	ordinals    map[uint16]uint64
+	forwards    map[uint16]string
+	initialized bool
+}
+
+func (m *module) mapImage(f *std_pe.File, data []byte) error {
+	var sizeOfImage, entry uint32
+	switch oh := f.OptionalHeader.(type) {
+	case *std_pe.OptionalHeader32:
+		m.imageBase, sizeOfImage, m.headers, entry = uint64(oh.ImageBase), oh.SizeOfImage, uint64(oh.SizeOfHeaders), oh.AddressOfEntryPoint
+		m.dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *std_pe.OptionalHeader64:
+		m.imageBase, sizeOfImage, m.headers, entry = oh.ImageBase, oh.SizeOfImage, uint64(oh.SizeOfHeaders), oh.AddressOfEntryPoint
+		m.dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+		m.pe64 = true
+	default:
+		return ErrSectionInvalid
+	}
+	m.dll = f.Characteristics&std_pe.IMAGE_FILE_DLL != 0
+	pageSize := m.dbg.Emulator().PageSize()
+	size := debugger.Align(uint64(sizeOfImage), pageSize)
+	if size == 0 {
+		return ErrSectionInvalid
+	}
+	var err error
+	if f.Characteristics&std_pe.IMAGE_FILE_RELOCS_STRIPPED != 0 {
+		m.region, err = m.dbg.MemMap(m.imageBase, size, emulator.MEM_PROT_ALL)
+	} else {
+		m.region, err = m.dbg.MapAlloc(size, emulator.MEM_PROT_ALL)
+	}
+	if err != nil {
+		return err
+	}
+	m.delta = m.region.Addr - m.imageBase
+	m.image = make([]byte, size)
+	copy(m.image[:min(m.headers, size)], data)
+	for _, sect := range f.Sections {
+		m.sections = append(m.sections, sect.SectionHeader)
+		raw := uint64(sect.Size)
+		if sect.VirtualSize != 0 {
+			raw = min(raw, uint64(sect.VirtualSize))
+		}
+		if uint64(sect.Offset)+raw > uint64(len(data)) || uint64(sect.VirtualAddress)+raw > size {
+			m.Close()
+			return ErrSectionInvalid
+		}
+		copy(m.image[sect.VirtualAddress:], data[sect.Offset:][:raw])
+	}
+	if entry != 0 {
+		m.entry = m.region.Addr + uint64(entry)
+	}
+	return nil
+}
+
+func (m *module) link() error {
+	err := m.dbg.Emulator().MemWrite(m.region.Addr, m.image)
+	if err != nil {
+		return err
+	}
+	m.image = nil
+	return m.protect()
+}
+
+func (m *module) protect() error {
+	pageSize := m.dbg.Emulator().PageSize()
+	prots := make([]emulator.MemProt, m.region.Size/pageSize)
+	for i := uint64(0); i < debugger.Align(m.headers, pageSize)/pageSize && i < uint64(len(prots)); i++ {
+		prots[i] = emulator.MEM_PROT_READ
+	}
+	for _, sect := range m.sections {
+		size := uint64(sect.VirtualSize)
+		if size == 0 {
+			size = uint64(sect.Size)
+		}
+		prot := toProt(sect.Characteristics)
+		begin := uint64(sect.VirtualAddress) / pageSize
+		end := debugger.Align(uint64(sect.VirtualAddress)+size, pageSize) / pageSize
+		for i := begin; i < end && i < uint64(len(prots)); i++ {
+			prots[i] |= prot
+		}
+	}
+	for begin := 0; begin < len(prots); {
+		end := begin + 1
+		for end < len(prots) && prots[end] == prots[begin] {
+			end++
+		}
+		err := m.dbg.MemProtect(m.region.Addr+uint64(begin)*pageSize, uint64(end-begin)*pageSize, prots[begin])
+		if err != nil {
+			return err
+		}
+		begin = end
+	}
+	return nil
+}
+
+func (m *module) directory(index int) ([]byte, uint32, bool) {
+	if index >= len(m.dirs) {
+		return nil, 0, false
+	}
+	dir := m.dirs[index]
+	if dir.VirtualAddress == 0 || dir.Size == 0 {
+		return nil, 0, false
+	}
+	data, ok := m.data(dir.VirtualAddress, dir.Size)
+	return data, dir.VirtualAddress, ok
+}
+
+func (m *module) data(rva, size uint32) ([]byte, bool) {
+	if uint64(rva)+uint64(size) > uint64(len(m.image)) {
+		return nil, false
+	}
+	return m.image[rva:][:size], true
+}
+
+func (m *module) cstring(rva uint32) (string, bool) {
+	if uint64(rva) >= uint64(len(m.image)) {
+		return "", false
+	}
+	s, _, ok := bytes.Cut(m.image[rva:], []byte{0})
+	return string(s), ok
+}
+
+func (m *module) wordSize() uint32 {
+	if m.pe64 {
+		return 8
+	}
+	return 4
+}
+
+func (m *module) word(b []byte) uint64 {
+	if m.pe64 {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return uint64(binary.LittleEndian.Uint32(b))
+}
+
+func (m *module) putWord(b []byte, v uint64) {
+	if m.pe64 {
+		binary.LittleEndian.PutUint64(b, v)
+	} else {
+		binary.LittleEndian.PutUint32(b, uint32(v))
+	}
+}
+
+func (m *module) resolve(dll, name string, unresolved []UnresolvedCallback) (uint64, error) {
+	if module, err := m.findModule(dll); err == nil {
+		if addr, err := module.FindSymbol(name); err == nil {
+			return addr, nil
+		}
+	}
+	for _, callback := range unresolved {
+		addr, err := callback(m, dll, name)
+		if err == nil {
+			return addr, nil
+		}
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) findModule(dll string) (debugger.Module, error) {
+	module, err := m.dbg.FindModule(dll)
+	if err == nil {
+		return module, nil
+	}
+	if lower := strings.ToLower(dll); lower != dll {
+		return m.dbg.FindModule(lower)
+	}
+	return nil, err
+}
+
+func (m *module) Close() error {
+	if m.region.Size == 0 {
+		return nil
+	}
+	err := m.dbg.MapFree(m.region.Addr, m.region.Size)
+	m.region = emulator.MemRegion{}
+	return err
+}
+
+func (m *module) Name() string {
+	return m.name
+}
+
+func (m *module) Region() (uint64, uint64) {
+	return m.region.Addr, m.region.Size
+}
+
+func (m *module) BaseAddr() uint64 {
+	return m.region.Addr
+}
+
+func (m *module) EntryAddr() uint64 {
+	return m.entry
+}
+
+func (m *module) FindSymbol(name string) (uint64, error) {
+	if ordinal, ok := m.names[name]; ok {
+		return m.FindOrdinal(ordinal)
+	} else if s, ok := strings.CutPrefix(name, "#"); ok {
+		if ordinal, err := strconv.ParseUint(s, 10, 16); err == nil {
+			return m.FindOrdinal(uint16(ordinal))
+		}
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) FindOrdinal(ordinal uint16) (uint64, error) {
+	if addr, ok := m.ordinals[ordinal]; ok {
+		return addr, nil
+	} else if forward, ok := m.forwards[ordinal]; ok {
+		dll, name, _ := strings.Cut(forward, ".")
+		module, err := m.findModule(dll + ".dll")
+		if err != nil || module == debugger.Module(m) {
+			return 0, debugger.ErrSymbolNotFound
+		}
+		return module.FindSymbol(name)
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) Symbols(yield func(debugger.Symbol) bool) {
+	for _, name := range slices.Sorted(maps.Keys(m.names)) {
+		addr, ok := m.ordinals[m.names[name]]
+		if !ok {
+			continue
+		}
+		if !yield(debugger.Symbol{Name: name, Value: addr}) {
+			return
+		}
+	}
+}
+
+func (m *module) Needed() []string {
+	return m.needed
+}
+
+func toProt(characteristics uint32) emulator.MemProt {
+	var prot emulator.MemProt
+	if characteristics&std_pe.IMAGE_SCN_MEM_READ != 0 {
+		prot |= emulator.MEM_PROT_READ
+	}
+	if characteristics&std_pe.IMAGE_SCN_MEM_WRITE != 0 {
+		prot |= emulator.MEM_PROT_WRITE
+	}
+	if characteristics&std_pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+		prot |= emulator.MEM_PROT_EXEC
+	}
+	return prot
+}
@@ -0,0 +1,72 @@
+package pe
+
+import (
+	"bytes"
+	std_pe "debug/pe"
+	"io/fs"
+	"path"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type Module interface {
+	debugger.Module
+	debugger.SymbolIter
+	Needed() []string
+	FindOrdinal(ordinal uint16) (uint64, error)
+}
+
+type UnresolvedCallback = func(module Module, dll, name string) (uint64, error)
+
+func Load(dbg debugger.Debugger, fsys filesystem.FS, name string, unresolved ...UnresolvedCallback) (Module, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := std_pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var arch emulator.Arch
+	switch f.Machine {
+	case std_pe.IMAGE_FILE_MACHINE_I386:
+		arch = emulator.ARCH_X86
+	case std_pe.IMAGE_FILE_MACHINE_AMD64:
+		arch = emulator.ARCH_X86_64
+	default:
+		return nil, emulator.ErrArchUnsupported
+	}
+	if arch != dbg.Arch() {
+		return nil, emulator.ErrArchMismatch
+	}
+	m := &module{
+		dbg:      dbg,
+		name:     path.Base(name),
+		names:    make(map[string]uint16),
+		ordinals: make(map[uint16]uint64),
+		forwards: make(map[uint16]string),
+	}
+	err = m.mapImage(f, data)
+	if err != nil {
+		return nil, err
+	}
+	err = m.relocate()
+	if err == nil {
+		err = m.parseExports()
+	}
+	if err == nil {
+		err = m.bindImports(func(dll, name string) (uint64, error) {
+			return m.resolve(dll, name, unresolved)
+		})
+	}
+	if err == nil {
+		err = m.link()
+	}
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
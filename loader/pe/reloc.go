@@ -0,0 +1,169 @@
+package pe
+
+import (
+	std_pe "debug/pe"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+const (
+	IMAGE_REL_BASED_ABSOLUTE = 0
+	IMAGE_REL_BASED_HIGH     = 1
+	IMAGE_REL_BASED_LOW      = 2
+	IMAGE_REL_BASED_HIGHLOW  = 3
+	IMAGE_REL_BASED_DIR64    = 10
+)
+
+func (m *module) relocate() error {
+	if m.delta == 0 {
+		return nil
+	}
+	data, _, ok := m.directory(std_pe.IMAGE_DIRECTORY_ENTRY_BASERELOC)
+	if !ok {
+		return nil
+	}
+	for len(data) >= 8 {
+		page := binary.LittleEndian.Uint32(data)
+		size := binary.LittleEndian.Uint32(data[4:])
+		if size < 8 || uint64(size) > uint64(len(data)) {
+			return ErrRelocInvalid
+		}
+		for entries := data[8:size]; len(entries) >= 2; entries = entries[2:] {
+			entry := binary.LittleEndian.Uint16(entries)
+			var width uint32
+			switch entry >> 12 {
+			case IMAGE_REL_BASED_ABSOLUTE:
+				continue
+			case IMAGE_REL_BASED_HIGH, IMAGE_REL_BASED_LOW:
+				width = 2
+			case IMAGE_REL_BASED_HIGHLOW:
+				width = 4
+			case IMAGE_REL_BASED_DIR64:
+				width = 8
+			default:
+				return ErrRelocInvalid
+			}
+			b, ok := m.data(page+uint32(entry&0xfff), width)
+			if !ok {
+				return ErrRelocInvalid
+			}
+			switch entry >> 12 {
+			case IMAGE_REL_BASED_HIGH:
+				binary.LittleEndian.PutUint16(b, binary.LittleEndian.Uint16(b)+uint16(m.delta>>16))
+			case IMAGE_REL_BASED_LOW:
+				binary.LittleEndian.PutUint16(b, binary.LittleEndian.Uint16(b)+uint16(m.delta))
+			case IMAGE_REL_BASED_HIGHLOW:
+				binary.LittleEndian.PutUint32(b, binary.LittleEndian.Uint32(b)+uint32(m.delta))
+			case IMAGE_REL_BASED_DIR64:
+				binary.LittleEndian.PutUint64(b, binary.LittleEndian.Uint64(b)+m.delta)
+			}
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+func (m *module) bindImports(resolve func(dll, name string) (uint64, error)) error {
+	data, _, ok := m.directory(std_pe.IMAGE_DIRECTORY_ENTRY_IMPORT)
+	if !ok {
+		return nil
+	}
+	ws := m.wordSize()
+	ordinalFlag := uint64(1) << (ws*8 - 1)
+	for ; len(data) >= 20; data = data[20:] {
+		lookup := binary.LittleEndian.Uint32(data)
+		nameRVA := binary.LittleEndian.Uint32(data[12:])
+		iat := binary.LittleEndian.Uint32(data[16:])
+		if nameRVA == 0 && iat == 0 {
+			break
+		}
+		dll, ok := m.cstring(nameRVA)
+		if !ok {
+			return ErrImportInvalid
+		}
+		m.needed = append(m.needed, dll)
+		if lookup == 0 {
+			lookup = iat
+		}
+		for i := uint32(0); ; i++ {
+			thunk, ok := m.data(lookup+i*ws, ws)
+			if !ok {
+				return ErrImportInvalid
+			}
+			slot, ok := m.data(iat+i*ws, ws)
+			if !ok {
+				return ErrImportInvalid
+			}
+			entry := m.word(thunk)
+			if entry == 0 {
+				break
+			}
+			var name string
+			if entry&ordinalFlag != 0 {
+				name = "#" + strconv.FormatUint(entry&0xffff, 10)
+			} else if name, ok = m.cstring(uint32(entry) + 2); !ok {
+				return ErrImportInvalid
+			}
+			addr, err := resolve(dll, name)
+			if err != nil {
+				return fmt.Errorf("%w: %s!%s", err, dll, name)
+			}
+			m.putWord(slot, addr)
+		}
+	}
+	return nil
+}
+
+func (m *module) parseExports() error {
+	data, rva, ok := m.directory(std_pe.IMAGE_DIRECTORY_ENTRY_EXPORT)
+	if !ok {
+		return nil
+	} else if len(data) < 40 {
+		return ErrExportInvalid
+	}
+	end := rva + uint32(len(data))
+	base := binary.LittleEndian.Uint32(data[16:])
+	funcCount := binary.LittleEndian.Uint32(data[20:])
+	nameCount := binary.LittleEndian.Uint32(data[24:])
+	funcs, ok := m.data(binary.LittleEndian.Uint32(data[28:]), funcCount*4)
+	if !ok {
+		return ErrExportInvalid
+	}
+	names, ok := m.data(binary.LittleEndian.Uint32(data[32:]), nameCount*4)
+	if !ok {
+		return ErrExportInvalid
+	}
+	indexes, ok := m.data(binary.LittleEndian.Uint32(data[36:]), nameCount*2)
+	if !ok {
+		return ErrExportInvalid
+	}
+	for i := range funcCount {
+		addr := binary.LittleEndian.Uint32(funcs[i*4:])
+		if addr == 0 {
+			continue
+		}
+		ordinal := uint16(base + i)
+		if addr >= rva && addr < end {
+			forward, ok := m.cstring(addr)
+			if !ok {
+				return ErrExportInvalid
+			}
+			m.forwards[ordinal] = forward
+		} else {
+			m.ordinals[ordinal] = m.region.Addr + uint64(addr)
+		}
+	}
+	for i := range nameCount {
+		index := uint32(binary.LittleEndian.Uint16(indexes[i*2:]))
+		if index >= funcCount {
+			return ErrExportInvalid
+		}
+		name, ok := m.cstring(binary.LittleEndian.Uint32(names[i*4:]))
+		if !ok {
+			return ErrExportInvalid
+		}
+		m.names[name] = uint16(base + index)
+	}
+	return nil
+}
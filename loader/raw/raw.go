@@ -0,0 +1,153 @@
+package raw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/filesystem"
+)
+
+type Range struct {
+	Offset, Size uint64
+	Prot         emulator.MemProt
+}
+
+type Config struct {
+	Name    string
+	Base    uint64
+	Entry   uint64
+	Prot    emulator.MemProt
+	Ranges  []Range
+	Symbols map[string]uint64
+}
+
+type Module interface {
+	debugger.Module
+	debugger.SymbolIter
+}
+
+type module struct {
+	dbg     debugger.Debugger
+	name    string
+	region  emulator.MemRegion
+	entry   uint64
+	symbols map[string]uint64
+}
+
+func Load(dbg debugger.Debugger, data []byte, config Config) (Module, error) {
+	size := uint64(len(data))
+	if size == 0 {
+		return nil, debugger.ErrArgumentInvalid
+	}
+	prot := config.Prot
+	if prot == emulator.MEM_PROT_NONE {
+		prot = emulator.MEM_PROT_ALL
+	}
+	var region emulator.MemRegion
+	var err error
+	if config.Base == 0 {
+		region, err = dbg.MapAlloc(size, prot)
+	} else {
+		pageSize := dbg.Emulator().PageSize()
+		if config.Base&(pageSize-1) != 0 {
+			return nil, debugger.ErrAddressInvalid
+		}
+		region, err = dbg.MemMap(config.Base, size, prot)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &module{
+		dbg:     dbg,
+		name:    config.Name,
+		region:  region,
+		entry:   region.Addr + config.Entry,
+		symbols: make(map[string]uint64, len(config.Symbols)),
+	}
+	err = dbg.Emulator().MemWrite(region.Addr, data)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	for _, r := range config.Ranges {
+		if r.Offset+r.Size > region.Size {
+			m.Close()
+			return nil, debugger.ErrArgumentInvalid
+		}
+		err = dbg.MemProtect(region.Addr+r.Offset, r.Size, r.Prot)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	for name, offset := range config.Symbols {
+		m.symbols[name] = region.Addr + offset
+	}
+	return m, nil
+}
+
+func LoadFile(dbg debugger.Debugger, file filesystem.File, config Config) (Module, error) {
+	r, ok := file.(filesystem.ReadFile)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	if config.Name == "" {
+		if info, err := file.Stat(); err == nil {
+			config.Name = info.Name()
+		}
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Load(dbg, data, config)
+}
+
+func (m *module) Close() error {
+	if m.region.Size == 0 {
+		return nil
+	}
+	err := m.dbg.MapFree(m.region.Addr, m.region.Size)
+	m.region = emulator.MemRegion{}
+	return err
+}
+
+func (m *module) Name() string {
+	return m.name
+}
+
+func (m *module) Region() (uint64, uint64) {
+	return m.region.Addr, m.region.Size
+}
+
+func (m *module) BaseAddr() uint64 {
+	return m.region.Addr
+}
+
+func (m *module) EntryAddr() uint64 {
+	return m.entry
+}
+
+func (m *module) Init(ctx context.Context) error {
+	return nil
+}
+
+func (m *module) FindSymbol(name string) (uint64, error) {
+	if addr, ok := m.symbols[name]; ok {
+		return addr, nil
+	}
+	return 0, debugger.ErrSymbolNotFound
+}
+
+func (m *module) Symbols(yield func(debugger.Symbol) bool) {
+	for _, name := range slices.Sorted(maps.Keys(m.symbols)) {
+		if !yield(debugger.Symbol{Name: name, Value: m.symbols[name]}) {
+			return
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package windows
+
+const (
+	ERROR_SUCCESS           = 0
+	ERROR_INVALID_HANDLE    = 6
+	ERROR_NOT_ENOUGH_MEMORY = 8
+	ERROR_INVALID_PARAMETER = 87
+	ERROR_MOD_NOT_FOUND     = 126
+	ERROR_PROC_NOT_FOUND    = 127
+	ERROR_INVALID_ADDRESS   = 487
+)
@@ -0,0 +1,242 @@
+package windows
+
+import (
+	"encoding/binary"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+)
+
+const (
+	MEM_COMMIT   = 0x1000
+	MEM_RESERVE  = 0x2000
+	MEM_DECOMMIT = 0x4000
+	MEM_RELEASE  = 0x8000
+
+	PAGE_NOACCESS          = 0x01
+	PAGE_READONLY          = 0x02
+	PAGE_READWRITE         = 0x04
+	PAGE_WRITECOPY         = 0x08
+	PAGE_EXECUTE           = 0x10
+	PAGE_EXECUTE_READ      = 0x20
+	PAGE_EXECUTE_READWRITE = 0x40
+	PAGE_EXECUTE_WRITECOPY = 0x80
+
+	HEAP_ZERO_MEMORY = 0x08
+)
+
+type virtualRegion struct {
+	size    uint64
+	protect uint32
+}
+
+func (w *windows) registerMemory() {
+	w.register("kernel32", "VirtualAlloc", Function{4, w.virtualAlloc})
+	w.register("kernel32", "VirtualFree", Function{3, w.virtualFree})
+	w.register("kernel32", "VirtualProtect", Function{4, w.virtualProtect})
+	w.register("kernel32", "GetProcessHeap", Function{0, w.getProcessHeap})
+	w.register("kernel32", "HeapAlloc", Function{3, w.heapAlloc})
+	w.register("kernel32", "HeapFree", Function{3, w.heapFree})
+	w.register("kernel32", "HeapReAlloc", Function{4, w.heapReAlloc})
+	w.register("kernel32", "HeapSize", Function{3, w.heapSize})
+	w.register("ntdll", "RtlAllocateHeap", Function{3, w.heapAlloc})
+	w.register("ntdll", "RtlFreeHeap", Function{3, w.heapFree})
+}
+
+func (w *windows) virtualAlloc(ctx debugger.Context, data any) {
+	var addr, size uintptr
+	var allocType, protect uint32
+	if !w.args(ctx, &addr, &size, &allocType, &protect) {
+		return
+	} else if size == 0 || allocType&(MEM_COMMIT|MEM_RESERVE) == 0 {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	pageSize := w.dbg.Emulator().PageSize()
+	base := uint64(addr) &^ (pageSize - 1)
+	length := debugger.Align(uint64(addr)+uint64(size), pageSize) - base
+	prot := toProt(protect)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if addr != 0 {
+		if _, _, ok := w.findVirtual(base); ok {
+			if w.dbg.MemProtect(base, length, prot) != nil {
+				w.SetLastError(ERROR_INVALID_ADDRESS)
+				base = 0
+			}
+			ctx.RetWrite(uintptr(base))
+			return
+		}
+	}
+	var region emulator.MemRegion
+	var err error
+	if addr != 0 {
+		region, err = w.dbg.MemMap(base, length, prot)
+	} else {
+		region, err = w.dbg.MapAlloc(length, prot)
+	}
+	if err != nil {
+		w.SetLastError(ERROR_NOT_ENOUGH_MEMORY)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	w.virtual[region.Addr] = virtualRegion{size: region.Size, protect: protect}
+	ctx.RetWrite(uintptr(region.Addr))
+}
+
+func (w *windows) virtualFree(ctx debugger.Context, data any) {
+	var addr, size uintptr
+	var freeType uint32
+	if !w.args(ctx, &addr, &size, &freeType) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	switch {
+	case freeType&MEM_RELEASE != 0:
+		region, ok := w.virtual[uint64(addr)]
+		if !ok || size != 0 {
+			w.SetLastError(ERROR_INVALID_PARAMETER)
+			ctx.RetWrite(uint32(0))
+			return
+		}
+		delete(w.virtual, uint64(addr))
+		err = w.dbg.MapFree(uint64(addr), region.size)
+	case freeType&MEM_DECOMMIT != 0:
+		err = w.dbg.MemProtect(uint64(addr), uint64(size), emulator.MEM_PROT_NONE)
+	default:
+		err = debugger.ErrArgumentInvalid
+	}
+	if err != nil {
+		w.SetLastError(ERROR_INVALID_ADDRESS)
+		ctx.RetWrite(uint32(0))
+		return
+	}
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) virtualProtect(ctx debugger.Context, data any) {
+	var addr, size, oldProtect uintptr
+	var protect uint32
+	if !w.args(ctx, &addr, &size, &protect, &oldProtect) {
+		return
+	}
+	pageSize := w.dbg.Emulator().PageSize()
+	base := uint64(addr) &^ (pageSize - 1)
+	length := debugger.Align(uint64(addr)+uint64(size), pageSize) - base
+	if w.dbg.MemProtect(base, length, toProt(protect)) != nil {
+		w.SetLastError(ERROR_INVALID_ADDRESS)
+		ctx.RetWrite(uint32(0))
+		return
+	}
+	old := uint32(PAGE_EXECUTE_READWRITE)
+	w.mu.Lock()
+	if start, region, ok := w.findVirtual(base); ok {
+		old = region.protect
+		if start == base && length == region.size {
+			region.protect = protect
+			w.virtual[start] = region
+		}
+	}
+	w.mu.Unlock()
+	if oldProtect != 0 {
+		ctx.ToPointer(uint64(oldProtect)).MemWrite(binary.LittleEndian.AppendUint32(nil, old))
+	}
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) getProcessHeap(ctx debugger.Context, data any) {
+	ctx.RetWrite(uintptr(w.heap))
+}
+
+func (w *windows) heapAlloc(ctx debugger.Context, data any) {
+	var heap, size uintptr
+	var flags uint32
+	if !w.args(ctx, &heap, &flags, &size) {
+		return
+	}
+	addr, err := w.dbg.MemAlloc(max(uint64(size), 1))
+	if err != nil {
+		w.SetLastError(ERROR_NOT_ENOUGH_MEMORY)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	if flags&HEAP_ZERO_MEMORY != 0 {
+		ctx.ToPointer(addr).MemWrite(make([]byte, size))
+	}
+	ctx.RetWrite(uintptr(addr))
+}
+
+func (w *windows) heapFree(ctx debugger.Context, data any) {
+	var heap, ptr uintptr
+	var flags uint32
+	if !w.args(ctx, &heap, &flags, &ptr) {
+		return
+	}
+	if ptr != 0 && w.dbg.MemFree(uint64(ptr)) != nil {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uint32(0))
+		return
+	}
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) heapReAlloc(ctx debugger.Context, data any) {
+	var heap, ptr, size uintptr
+	var flags uint32
+	if !w.args(ctx, &heap, &flags, &ptr, &size) {
+		return
+	}
+	old := w.dbg.MemSize(uint64(ptr))
+	addr, err := w.dbg.MemAlloc(max(uint64(size), 1))
+	if err != nil {
+		w.SetLastError(ERROR_NOT_ENOUGH_MEMORY)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	buf, err := ctx.ToPointer(uint64(ptr)).MemRead(min(old, uint64(size)))
+	if err == nil {
+		if flags&HEAP_ZERO_MEMORY != 0 && uint64(size) > old {
+			buf = append(buf, make([]byte, uint64(size)-old)...)
+		}
+		ctx.ToPointer(addr).MemWrite(buf)
+	}
+	w.dbg.MemFree(uint64(ptr))
+	ctx.RetWrite(uintptr(addr))
+}
+
+func (w *windows) heapSize(ctx debugger.Context, data any) {
+	var heap, ptr uintptr
+	var flags uint32
+	if !w.args(ctx, &heap, &flags, &ptr) {
+		return
+	}
+	ctx.RetWrite(uintptr(w.dbg.MemSize(uint64(ptr))))
+}
+
+func (w *windows) findVirtual(addr uint64) (uint64, virtualRegion, bool) {
+	for base, region := range w.virtual {
+		if addr >= base && addr < base+region.size {
+			return base, region, true
+		}
+	}
+	return 0, virtualRegion{}, false
+}
+
+func toProt(protect uint32) emulator.MemProt {
+	switch protect &^ 0x700 {
+	case PAGE_READONLY:
+		return emulator.MEM_PROT_READ
+	case PAGE_READWRITE, PAGE_WRITECOPY:
+		return emulator.MEM_PROT_READ | emulator.MEM_PROT_WRITE
+	case PAGE_EXECUTE:
+		return emulator.MEM_PROT_EXEC
+	case PAGE_EXECUTE_READ:
+		return emulator.MEM_PROT_READ | emulator.MEM_PROT_EXEC
+	case PAGE_EXECUTE_READWRITE, PAGE_EXECUTE_WRITECOPY:
+		return emulator.MEM_PROT_ALL
+	}
+	return emulator.MEM_PROT_NONE
+}
@@ -0,0 +1,143 @@
+package windows
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wnxd/microdbg/debugger"
+)
+
+func (w *windows) registerModule() {
+	w.register("kernel32", "LoadLibraryA", Function{1, w.loadLibrary(false)})
+	w.register("kernel32", "LoadLibraryW", Function{1, w.loadLibrary(true)})
+	w.register("kernel32", "LoadLibraryExA", Function{3, w.loadLibrary(false)})
+	w.register("kernel32", "LoadLibraryExW", Function{3, w.loadLibrary(true)})
+	w.register("kernel32", "GetModuleHandleA", Function{1, w.getModuleHandle(false)})
+	w.register("kernel32", "GetModuleHandleW", Function{1, w.getModuleHandle(true)})
+	w.register("kernel32", "GetProcAddress", Function{2, w.getProcAddress})
+	w.register("kernel32", "FreeLibrary", Function{1, w.freeLibrary})
+}
+
+func (w *windows) loadLibrary(wide bool) debugger.ControlCallback {
+	return func(ctx debugger.Context, data any) {
+		var name uintptr
+		if !w.args(ctx, &name) {
+			return
+		}
+		s, err := w.readString(uint64(name), wide)
+		if err != nil {
+			w.SetLastError(ERROR_INVALID_PARAMETER)
+			ctx.RetWrite(uintptr(0))
+			return
+		}
+		handle := w.findHandle(s)
+		if handle == 0 {
+			for _, loader := range w.loaders {
+				if module, err := loader(s); err == nil {
+					handle = module.BaseAddr()
+					break
+				}
+			}
+		}
+		if handle == 0 {
+			w.SetLastError(ERROR_MOD_NOT_FOUND)
+		}
+		ctx.RetWrite(uintptr(handle))
+	}
+}
+
+func (w *windows) getModuleHandle(wide bool) debugger.ControlCallback {
+	return func(ctx debugger.Context, data any) {
+		var name uintptr
+		if !w.args(ctx, &name) {
+			return
+		}
+		if name == 0 {
+			base, _ := w.readWord(w.PEB() + w.layout.pebImageBase)
+			ctx.RetWrite(uintptr(base))
+			return
+		}
+		var handle uint64
+		if s, err := w.readString(uint64(name), wide); err == nil {
+			handle = w.findHandle(s)
+		}
+		if handle == 0 {
+			w.SetLastError(ERROR_MOD_NOT_FOUND)
+		}
+		ctx.RetWrite(uintptr(handle))
+	}
+}
+
+func (w *windows) getProcAddress(ctx debugger.Context, data any) {
+	var handle, proc uintptr
+	if !w.args(ctx, &handle, &proc) {
+		return
+	}
+	var name string
+	if proc < 0x10000 {
+		name = fmt.Sprintf("#%d", proc)
+	} else if s, err := w.readString(uint64(proc), false); err == nil {
+		name = s
+	} else {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	dll := w.handleName(uint64(handle))
+	if module := w.dbg.GetModule(uint64(handle)); module != nil {
+		if addr, err := module.FindSymbol(name); err == nil {
+			ctx.RetWrite(uintptr(addr))
+			return
+		}
+	} else if dll == "" {
+		w.SetLastError(ERROR_INVALID_HANDLE)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	addr, err := w.Resolve(nil, dll, name)
+	if err != nil {
+		w.SetLastError(ERROR_PROC_NOT_FOUND)
+	}
+	ctx.RetWrite(uintptr(addr))
+}
+
+func (w *windows) freeLibrary(ctx debugger.Context, data any) {
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) findHandle(name string) uint64 {
+	dll := normalize(name[strings.LastIndexAny(name, `\/`)+1:])
+	for module := range w.dbg.Modules {
+		if normalize(module.Name()) == dll {
+			return module.BaseAddr()
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.functions[dll]; !ok {
+		return 0
+	} else if handle, ok := w.handles[dll]; ok {
+		return handle
+	}
+	handle, err := w.dbg.MemAlloc(uint64(len(dll)) + 1)
+	if err != nil {
+		return 0
+	}
+	w.dbg.ToPointer(handle).MemWrite(append([]byte(dll), 0))
+	w.handles[dll] = handle
+	return handle
+}
+
+func (w *windows) handleName(handle uint64) string {
+	if module := w.dbg.GetModule(handle); module != nil {
+		return module.Name()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for dll, h := range w.handles {
+		if h == handle {
+			return dll
+		}
+	}
+	return ""
+}
@@ -0,0 +1,278 @@
+package windows
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	emu_x86 "github.com/wnxd/microdbg/emulator/x86"
+)
+
+const (
+	processID = 0x1000
+	tlsSlots  = 64
+
+	osMajorVersion = 10
+	osMinorVersion = 0
+	osBuildNumber  = 19045
+	osPlatformID   = 2
+)
+
+type layout struct {
+	ws      uint64
+	segment emulator.Reg
+
+	tebSize       uint64
+	tebStackBase  uint64
+	tebStackLimit uint64
+	tebSelf       uint64
+	tebClientID   uint64
+	tebPEB        uint64
+	tebLastError  uint64
+	tebTlsSlots   uint64
+
+	pebImageBase  uint64
+	pebLdr        uint64
+	pebHeap       uint64
+	pebProcessors uint64
+	pebOSVersion  uint64
+
+	ldrSize  uint64
+	ldrLists uint64
+}
+
+func newLayout(arch emulator.Arch) (*layout, error) {
+	switch arch {
+	case emulator.ARCH_X86:
+		return &layout{
+			ws:            4,
+			segment:       emu_x86.X86_REG_FS_BASE,
+			tebSize:       0x1000,
+			tebStackBase:  0x04,
+			tebStackLimit: 0x08,
+			tebSelf:       0x18,
+			tebClientID:   0x20,
+			tebPEB:        0x30,
+			tebLastError:  0x34,
+			tebTlsSlots:   0xe10,
+			pebImageBase:  0x08,
+			pebLdr:        0x0c,
+			pebHeap:       0x18,
+			pebProcessors: 0x64,
+			pebOSVersion:  0xa4,
+			ldrSize:       0x30,
+			ldrLists:      0x0c,
+		}, nil
+	case emulator.ARCH_X86_64:
+		return &layout{
+			ws:            8,
+			segment:       emu_x86.X86_REG_GS_BASE,
+			tebSize:       0x2000,
+			tebStackBase:  0x08,
+			tebStackLimit: 0x10,
+			tebSelf:       0x30,
+			tebClientID:   0x40,
+			tebPEB:        0x60,
+			tebLastError:  0x68,
+			tebTlsSlots:   0x1480,
+			pebImageBase:  0x10,
+			pebLdr:        0x18,
+			pebHeap:       0x30,
+			pebProcessors: 0xb8,
+			pebOSVersion:  0x118,
+			ldrSize:       0x58,
+			ldrLists:      0x10,
+		}, nil
+	}
+	return nil, emulator.ErrArchUnsupported
+}
+
+func (l *layout) entrySize() uint64 {
+	return 13 * l.ws
+}
+
+func (w *windows) TEB() uint64 {
+	return w.region.Addr
+}
+
+func (w *windows) PEB() uint64 {
+	return w.region.Addr + w.layout.tebSize
+}
+
+func (w *windows) ldr() uint64 {
+	return w.PEB() + 0x800
+}
+
+func (w *windows) Attach(ctx debugger.Context) error {
+	l := w.layout
+	err := ctx.RegWrite(l.segment, w.TEB())
+	if err != nil {
+		return err
+	}
+	sp, err := ctx.RegRead(ctx.SP())
+	if err != nil {
+		return err
+	}
+	base := debugger.Align(sp, w.dbg.Emulator().PageSize())
+	stack := w.appendWord(nil, base)
+	stack = w.appendWord(stack, base-w.dbg.StackSize())
+	return w.dbg.ToPointer(w.TEB() + l.tebStackBase).MemWrite(stack)
+}
+
+func (w *windows) LastError() uint32 {
+	buf, err := w.dbg.ToPointer(w.TEB() + w.layout.tebLastError).MemRead(4)
+	if err != nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(buf)
+}
+
+func (w *windows) SetLastError(code uint32) error {
+	return w.dbg.ToPointer(w.TEB() + w.layout.tebLastError).MemWrite(binary.LittleEndian.AppendUint32(nil, code))
+}
+
+func (w *windows) initEnvironment() error {
+	l := w.layout
+	heap, err := w.dbg.MemAlloc(l.ws)
+	if err != nil {
+		return err
+	}
+	w.heap = heap
+	pageSize := w.dbg.Emulator().PageSize()
+	w.region, err = w.dbg.MapAlloc(debugger.Align(l.tebSize+0x1000, pageSize), emulator.MEM_PROT_READ|emulator.MEM_PROT_WRITE)
+	if err != nil {
+		return err
+	}
+	teb, peb, ldr := w.TEB(), w.PEB(), w.ldr()
+	fields := []struct {
+		addr  uint64
+		value uint64
+	}{
+		{teb, ^uint64(0)},
+		{teb + l.tebSelf, teb},
+		{teb + l.tebClientID, processID},
+		{teb + l.tebClientID + l.ws, 4},
+		{teb + l.tebPEB, peb},
+		{peb + l.pebLdr, ldr},
+		{peb + l.pebHeap, heap},
+	}
+	for _, field := range fields {
+		if err = w.writeWord(field.addr, field.value); err != nil {
+			return err
+		}
+	}
+	var info []byte
+	info = binary.LittleEndian.AppendUint32(info, osMajorVersion)
+	info = binary.LittleEndian.AppendUint32(info, osMinorVersion)
+	info = binary.LittleEndian.AppendUint16(info, osBuildNumber)
+	info = binary.LittleEndian.AppendUint16(info, 0)
+	info = binary.LittleEndian.AppendUint32(info, osPlatformID)
+	if err = w.dbg.ToPointer(peb + l.pebOSVersion).MemWrite(info); err != nil {
+		return err
+	}
+	if err = w.dbg.ToPointer(peb + l.pebProcessors).MemWrite(binary.LittleEndian.AppendUint32(nil, 1)); err != nil {
+		return err
+	}
+	header := binary.LittleEndian.AppendUint32(nil, uint32(l.ldrSize))
+	header = append(header, 1)
+	if err = w.dbg.ToPointer(ldr).MemWrite(header); err != nil {
+		return err
+	}
+	if err = w.dbg.Emulator().RegWrite(l.segment, teb); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	for module := range w.dbg.Modules {
+		w.addModule(module)
+	}
+	err = w.relink()
+	w.mu.Unlock()
+	w.closers = append(w.closers, w.dbg.OnModuleLoad(func(module debugger.Module) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.addModule(module) {
+			w.relink()
+		}
+	}), w.dbg.OnModuleUnload(func(module debugger.Module) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.removeModule(module) {
+			w.relink()
+		}
+	}))
+	return err
+}
+
+func (w *windows) addModule(module debugger.Module) bool {
+	if _, ok := w.entries[module]; ok || module.BaseAddr() == 0 {
+		return false
+	}
+	l := w.layout
+	name := utf16.Encode([]rune(module.Name()))
+	size := uint64(len(name)) * 2
+	entry, err := w.dbg.MemAlloc(l.entrySize() + size + 2)
+	if err != nil {
+		return false
+	}
+	_, imageSize := module.Region()
+	buf := make([]byte, 6*l.ws)
+	buf = w.appendWord(buf, module.BaseAddr())
+	buf = w.appendWord(buf, module.EntryAddr())
+	buf = w.appendWord(buf, uint64(uint32(imageSize)))
+	for range 2 {
+		str := binary.LittleEndian.AppendUint16(nil, uint16(size))
+		str = binary.LittleEndian.AppendUint16(str, uint16(size+2))
+		str = append(str, make([]byte, l.ws-4)...)
+		buf = append(buf, w.appendWord(str, entry+l.entrySize())...)
+	}
+	buf, _ = binary.Append(buf, binary.LittleEndian, name)
+	buf = append(buf, 0, 0)
+	if err = w.dbg.ToPointer(entry).MemWrite(buf); err != nil {
+		w.dbg.MemFree(entry)
+		return false
+	}
+	w.entries[module] = entry
+	w.order = append(w.order, module)
+	if len(w.order) == 1 {
+		w.writeWord(w.PEB()+l.pebImageBase, module.BaseAddr())
+	}
+	return true
+}
+
+func (w *windows) removeModule(module debugger.Module) bool {
+	entry, ok := w.entries[module]
+	if !ok {
+		return false
+	}
+	delete(w.entries, module)
+	for i, m := range w.order {
+		if m == module {
+			w.order = append(w.order[:i:i], w.order[i+1:]...)
+			break
+		}
+	}
+	w.dbg.MemFree(entry)
+	return true
+}
+
+func (w *windows) relink() error {
+	ws := w.layout.ws
+	for list := range uint64(3) {
+		head := w.ldr() + w.layout.ldrLists + list*2*ws
+		links := make([]uint64, 0, len(w.order)+2)
+		links = append(links, head)
+		for _, module := range w.order {
+			links = append(links, w.entries[module]+list*2*ws)
+		}
+		for i, link := range links {
+			next := links[(i+1)%len(links)]
+			prev := links[(i+len(links)-1)%len(links)]
+			err := w.dbg.ToPointer(link).MemWrite(w.appendWord(w.appendWord(nil, next), prev))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
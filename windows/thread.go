@@ -0,0 +1,117 @@
+package windows
+
+import (
+	"github.com/wnxd/microdbg/debugger"
+)
+
+const TLS_OUT_OF_INDEXES = 0xffffffff
+
+func (w *windows) registerThread() {
+	w.register("kernel32", "GetLastError", Function{0, w.getLastError})
+	w.register("kernel32", "SetLastError", Function{1, w.setLastError})
+	w.register("kernel32", "TlsAlloc", Function{0, w.tlsAlloc})
+	w.register("kernel32", "TlsFree", Function{1, w.tlsFree})
+	w.register("kernel32", "TlsGetValue", Function{1, w.tlsGetValue})
+	w.register("kernel32", "TlsSetValue", Function{2, w.tlsSetValue})
+	w.register("kernel32", "GetCurrentProcess", Function{0, w.getCurrentProcess})
+	w.register("kernel32", "GetCurrentThread", Function{0, w.getCurrentThread})
+	w.register("kernel32", "GetCurrentProcessId", Function{0, w.getCurrentProcessId})
+	w.register("kernel32", "GetCurrentThreadId", Function{0, w.getCurrentThreadId})
+	w.register("ntdll", "RtlGetLastWin32Error", Function{0, w.getLastError})
+	w.register("ntdll", "RtlSetLastWin32Error", Function{1, w.setLastError})
+	w.register("ntdll", "NtCurrentTeb", Function{0, w.ntCurrentTeb})
+}
+
+func (w *windows) getLastError(ctx debugger.Context, data any) {
+	ctx.RetWrite(w.LastError())
+}
+
+func (w *windows) setLastError(ctx debugger.Context, data any) {
+	var code uint32
+	if w.args(ctx, &code) {
+		w.SetLastError(code)
+	}
+}
+
+func (w *windows) tlsAlloc(ctx debugger.Context, data any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, used := range w.tls {
+		if !used {
+			w.tls[i] = true
+			w.writeWord(w.tlsSlot(uint32(i)), 0)
+			ctx.RetWrite(uint32(i))
+			return
+		}
+	}
+	w.SetLastError(ERROR_NOT_ENOUGH_MEMORY)
+	ctx.RetWrite(uint32(TLS_OUT_OF_INDEXES))
+}
+
+func (w *windows) tlsFree(ctx debugger.Context, data any) {
+	var index uint32
+	if !w.args(ctx, &index) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if index >= tlsSlots || !w.tls[index] {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uint32(0))
+		return
+	}
+	w.tls[index] = false
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) tlsGetValue(ctx debugger.Context, data any) {
+	var index uint32
+	if !w.args(ctx, &index) {
+		return
+	} else if index >= tlsSlots {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uintptr(0))
+		return
+	}
+	value, _ := w.readWord(w.tlsSlot(index))
+	w.SetLastError(ERROR_SUCCESS)
+	ctx.RetWrite(uintptr(value))
+}
+
+func (w *windows) tlsSetValue(ctx debugger.Context, data any) {
+	var index uint32
+	var value uintptr
+	if !w.args(ctx, &index, &value) {
+		return
+	} else if index >= tlsSlots {
+		w.SetLastError(ERROR_INVALID_PARAMETER)
+		ctx.RetWrite(uint32(0))
+		return
+	}
+	w.writeWord(w.tlsSlot(index), uint64(value))
+	ctx.RetWrite(uint32(1))
+}
+
+func (w *windows) getCurrentProcess(ctx debugger.Context, data any) {
+	ctx.RetWrite(^uintptr(0))
+}
+
+func (w *windows) getCurrentThread(ctx debugger.Context, data any) {
+	ctx.RetWrite(^uintptr(1))
+}
+
+func (w *windows) getCurrentProcessId(ctx debugger.Context, data any) {
+	ctx.RetWrite(uint32(processID))
+}
+
+func (w *windows) getCurrentThreadId(ctx debugger.Context, data any) {
+	ctx.RetWrite(uint32(ctx.TaskID() * 4))
+}
+
+func (w *windows) ntCurrentTeb(ctx debugger.Context, data any) {
+	ctx.RetWrite(uintptr(w.TEB()))
+}
+
+func (w *windows) tlsSlot(index uint32) uint64 {
+	return w.TEB() + w.layout.tebTlsSlots + uint64(index)*w.layout.ws
+}
@@ -0,0 +1,230 @@
+package windows
+
+import (
+	"encoding/binary"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/wnxd/microdbg/debugger"
+	"github.com/wnxd/microdbg/emulator"
+	"github.com/wnxd/microdbg/loader/pe"
+)
+
+type LoadCallback = func(name string) (debugger.Module, error)
+
+type Function struct {
+	Args     int
+	Callback debugger.ControlCallback
+}
+
+type Windows interface {
+	io.Closer
+	Register(dll, name string, fn Function)
+	Resolve(module pe.Module, dll, name string) (uint64, error)
+	Names(dll string) []string
+	Attach(ctx debugger.Context) error
+	TEB() uint64
+	PEB() uint64
+	LastError() uint32
+	SetLastError(code uint32) error
+}
+
+type stubKey struct {
+	dll  string
+	name string
+}
+
+type windows struct {
+	mu        sync.Mutex
+	dbg       debugger.Debugger
+	loaders   []LoadCallback
+	layout    *layout
+	region    emulator.MemRegion
+	closers   []io.Closer
+	functions map[string]map[string]Function
+	stubs     map[stubKey]debugger.ControlHandler
+	handles   map[string]uint64
+	entries   map[debugger.Module]uint64
+	order     []debugger.Module
+	heap      uint64
+	virtual   map[uint64]virtualRegion
+	tls       [tlsSlots]bool
+}
+
+func New(dbg debugger.Debugger, loaders ...LoadCallback) (Windows, error) {
+	l, err := newLayout(dbg.Arch())
+	if err != nil {
+		return nil, err
+	}
+	w := &windows{
+		dbg:       dbg,
+		loaders:   loaders,
+		layout:    l,
+		functions: make(map[string]map[string]Function),
+		stubs:     make(map[stubKey]debugger.ControlHandler),
+		handles:   make(map[string]uint64),
+		entries:   make(map[debugger.Module]uint64),
+		virtual:   make(map[uint64]virtualRegion),
+	}
+	w.registerModule()
+	w.registerMemory()
+	w.registerThread()
+	if err = w.initEnvironment(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *windows) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, closer := range w.closers {
+		closer.Close()
+	}
+	w.closers = nil
+	for _, stub := range w.stubs {
+		stub.Close()
+	}
+	clear(w.stubs)
+	for _, addr := range w.entries {
+		w.dbg.MemFree(addr)
+	}
+	clear(w.entries)
+	w.order = nil
+	for _, addr := range w.handles {
+		w.dbg.MemFree(addr)
+	}
+	clear(w.handles)
+	if w.region.Size == 0 {
+		return nil
+	}
+	err := w.dbg.MapFree(w.region.Addr, w.region.Size)
+	w.region = emulator.MemRegion{}
+	return err
+}
+
+func (w *windows) Register(dll, name string, fn Function) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.register(dll, name, fn)
+}
+
+func (w *windows) Resolve(module pe.Module, dll, name string) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	dll = normalize(dll)
+	if _, ok := w.functions[dll][name]; !ok {
+		dll = ""
+		for _, key := range slices.Sorted(maps.Keys(w.functions)) {
+			if _, ok = w.functions[key][name]; ok {
+				dll = key
+				break
+			}
+		}
+		if dll == "" {
+			return 0, debugger.ErrSymbolNotFound
+		}
+	}
+	key := stubKey{dll, name}
+	if stub, ok := w.stubs[key]; ok {
+		return stub.Addr(), nil
+	}
+	stub, err := w.dbg.AddControl(w.handleStub, key)
+	if err != nil {
+		return 0, err
+	}
+	w.stubs[key] = stub
+	return stub.Addr(), nil
+}
+
+func (w *windows) Names(dll string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Sorted(maps.Keys(w.functions[normalize(dll)]))
+}
+
+func (w *windows) register(dll, name string, fn Function) {
+	dll = normalize(dll)
+	functions, ok := w.functions[dll]
+	if !ok {
+		functions = make(map[string]Function)
+		w.functions[dll] = functions
+	}
+	functions[name] = fn
+}
+
+func (w *windows) handleStub(ctx debugger.Context, data any) {
+	key := data.(stubKey)
+	w.mu.Lock()
+	fn := w.functions[key.dll][key.name]
+	w.mu.Unlock()
+	if fn.Callback != nil {
+		fn.Callback(ctx, w)
+	}
+	if w.dbg.Arch() == emulator.ARCH_X86 {
+		ctx.ReturnPop(uint64(fn.Args) * w.dbg.PointerSize())
+	} else {
+		ctx.Return()
+	}
+}
+
+func (w *windows) calling() debugger.Calling {
+	if w.dbg.Arch() == emulator.ARCH_X86 {
+		return debugger.Calling_Stdcall
+	}
+	return debugger.Calling_Win64
+}
+
+func (w *windows) args(ctx debugger.Context, args ...any) bool {
+	return ctx.ArgExtract(w.calling(), args...) == nil
+}
+
+func (w *windows) readWord(addr uint64) (uint64, error) {
+	buf, err := w.dbg.ToPointer(addr).MemRead(w.dbg.PointerSize())
+	if err != nil {
+		return 0, err
+	} else if len(buf) == 4 {
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func (w *windows) writeWord(addr, value uint64) error {
+	return w.dbg.ToPointer(addr).MemWrite(w.appendWord(nil, value))
+}
+
+func (w *windows) appendWord(buf []byte, value uint64) []byte {
+	if w.dbg.PointerSize() == 4 {
+		return binary.LittleEndian.AppendUint32(buf, uint32(value))
+	}
+	return binary.LittleEndian.AppendUint64(buf, value)
+}
+
+func (w *windows) readString(addr uint64, wide bool) (string, error) {
+	if !wide {
+		return w.dbg.ToPointer(addr).MemReadString()
+	}
+	var chars []uint16
+	for {
+		buf, err := w.dbg.ToPointer(addr).MemRead(2)
+		if err != nil {
+			return "", err
+		}
+		c := binary.LittleEndian.Uint16(buf)
+		if c == 0 {
+			return string(utf16.Decode(chars)), nil
+		}
+		chars = append(chars, c)
+		addr += 2
+	}
+}
+
+func normalize(dll string) string {
+	dll = strings.ToLower(dll)
+	return strings.TrimSuffix(dll, ".dll")
+}